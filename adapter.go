@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"context"
 	"io"
 	"mime/multipart"
 	"time"
@@ -14,7 +15,28 @@ type Adapter interface {
 	TemporaryServingFile(bucket string, fileURL string, expiredTime time.Time, client interface{}) (string, error)
 	UploadBuffer(string, string, string) (*Buffer, error)
 	ReadFile(string, string) ([]byte, error)
+
+	UploadFileWithContext(ctx context.Context, file *multipart.FileHeader, bucket, name string) (string, error)
+	DeleteFileUsingURLWithContext(ctx context.Context, bucket, fileURL string) error
+	UploadReaderWithContext(context.Context, string, string, io.Reader, string) (string, error)
+	TemporaryServingFileWithContext(ctx context.Context, bucket string, fileURL string, expiredTime time.Time, client interface{}) (string, error)
+	UploadBufferWithContext(context.Context, string, string, string) (*Buffer, error)
+	ReadFileWithContext(context.Context, string, string) ([]byte, error)
+
+	List(ctx context.Context, bucket string, options ListOptions) (*ListResult, error)
+	Stat(ctx context.Context, bucket, name string) (*ObjectInfo, error)
+	OpenRangeReader(ctx context.Context, bucket, name string, offset, length int64) (io.ReadCloser, error)
+
+	DeleteObject(ctx context.Context, bucket, key string) error
+	TemporaryServingObject(ctx context.Context, bucket, key string, expiredTime time.Time, client interface{}) (string, error)
+	Put(ctx context.Context, bucket, name string, reader io.Reader, contentType string) (*UploadResult, error)
+
+	Close() error
 }
 
 var _ Adapter = &GCSAdapter{}
 var _ Adapter = &AliyunAdapter{}
+var _ Adapter = &LocalAdapter{}
+var _ Adapter = &MemoryAdapter{}
+var _ Adapter = &S3Adapter{}
+var _ Adapter = &AzureBlobAdapter{}
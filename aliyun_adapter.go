@@ -2,171 +2,622 @@ package storage
 
 import (
 	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
-	"log"
 	"mime/multipart"
 	"net/http"
 	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	oss "github.com/aliyun/aliyun-oss-go-sdk/oss"
 )
 
-// AliyunAdapter : The adapter empty because they do not have any data to share for now
+// AliyunAdapter : The oss client is created once and shared by every operation,
+// it is safe for concurrent use. With a CredentialsProvider the static keys are
+// ignored, and the client is rebuilt with fresh credentials before they expire
 type AliyunAdapter struct {
-	Endpoint        string
-	AccessKeyID     string
-	AccessKeySecret string
+	Endpoint            string
+	AccessKeyID         string
+	AccessKeySecret     string
+	SecurityToken       string
+	CredentialsProvider AliyunCredentialsProvider
+
+	mu         sync.Mutex
+	client     *oss.Client
+	expiration time.Time
+	refreshAt  time.Time
+	refreshErr error
+	refreshing chan struct{}
+}
+
+func init() {
+	Register(ALIYUN, func(config Config) (Adapter, error) {
+		var client AliyunClient
+		switch config := config.(type) {
+		case AliyunClient:
+			client = config
+		case *AliyunClient:
+			if config == nil {
+				return nil, invalidConfig(ALIYUN, config)
+			}
+			client = *config
+		default:
+			return nil, invalidConfig(ALIYUN, config)
+		}
+
+		adapter := new(AliyunAdapter)
+		adapter.Endpoint = client.Endpoint
+		adapter.AccessKeyID = client.AccessKeyID
+		adapter.AccessKeySecret = client.AccessKeySecret
+		adapter.SecurityToken = client.SecurityToken
+		adapter.CredentialsProvider = client.CredentialsProvider
+		if _, err := adapter.getClient(context.Background()); err != nil {
+			return nil, err
+		}
+		return adapter, nil
+	})
 }
 
 // UploadFile : Upload file to the bucket
 func (adapter *AliyunAdapter) UploadFile(file *multipart.FileHeader, bucket, filename string) (string, error) {
-	var reader io.Reader
-	name := ""
+	return adapter.UploadFileWithContext(context.Background(), file, bucket, filename)
+}
 
-	fileExt := strings.ToLower(strings.Split(file.Header.Get("Content-Type"), "/")[1])
+// UploadFileWithContext : Upload file to the bucket
+func (adapter *AliyunAdapter) UploadFileWithContext(ctx context.Context, file *multipart.FileHeader, bucket, filename string) (string, error) {
+	result, err := uploadMultipartFile(ctx, adapter, file, bucket, filename)
+	if err != nil {
+		return "", err
+	}
 
-	if ext, isExist := extensionMapper[fileExt]; isExist {
-		name = fmt.Sprintf("%s.%s", filename, ext)
-	} else {
-		name = fmt.Sprintf("%s.%s", filename, fileExt)
+	return result.URL, nil
+}
+
+// DeleteFileUsingURL : Delete file from the bucket using url
+func (adapter *AliyunAdapter) DeleteFileUsingURL(bucket, fileURL string) error {
+	return adapter.DeleteFileUsingURLWithContext(context.Background(), bucket, fileURL)
+}
+
+// DeleteFileUsingURLWithContext : Delete file from the bucket using url
+func (adapter *AliyunAdapter) DeleteFileUsingURLWithContext(ctx context.Context, bucket, fileURL string) error {
+	key, err := objectKeyFromURL(bucket, fileURL)
+	if err != nil {
+		return aliyunError("delete", bucket, fileURL, err)
 	}
 
-	src, err := file.Open()
+	return adapter.DeleteObject(ctx, bucket, key)
+}
+
+// DeleteObject : Delete the object by its key
+func (adapter *AliyunAdapter) DeleteObject(ctx context.Context, bucket, key string) error {
+	if err := ctx.Err(); err != nil {
+		return aliyunError("delete", bucket, key, err)
+	}
+
+	storageClient, err := adapter.getClient(ctx)
 	if err != nil {
-		return "", err
+		return aliyunError("delete", bucket, key, err)
 	}
 
-	defer src.Close()
-	reader = src
+	object, err := storageClient.Bucket(bucket)
+	if err != nil {
+		return aliyunError("delete", bucket, key, err)
+	}
 
-	fileURL, err := adapter.UploadReader(bucket, name, reader, strings.ToLower(fileExt))
+	return aliyunError("delete", bucket, key, object.DeleteObject(key))
+}
+
+// UpdateMetadata : Replace the headers with SetObjectMeta, which copies the object
+// onto itself. The headers which don't change are carried over from the object
+func (adapter *AliyunAdapter) UpdateMetadata(ctx context.Context, bucket, key string, attrs ObjectAttributes) error {
+	if err := ctx.Err(); err != nil {
+		return aliyunError("update", bucket, key, err)
+	}
+
+	if attrs.Tags != nil {
+		return aliyunError("update", bucket, key, errTagsNotSupported("aliyun"))
+	}
+
+	storageClient, err := adapter.getClient(ctx)
 	if err != nil {
-		return "", err
+		return aliyunError("update", bucket, key, err)
 	}
 
-	return fileURL, nil
+	object, err := storageClient.Bucket(bucket)
+	if err != nil {
+		return aliyunError("update", bucket, key, err)
+	}
+
+	source, err := object.GetObjectDetailedMeta(key)
+	if err != nil {
+		return aliyunError("update", bucket, key, err)
+	}
+
+	options, err := aliyunHeaderOptions(replaceHeaders(source, oss.HTTPHeaderOssMetaPrefix, "", attrs))
+	if err != nil {
+		return aliyunError("update", bucket, key, err)
+	}
+
+	return aliyunError("update", bucket, key, object.SetObjectMeta(key, options...))
 }
 
-// DeleteFileUsingURL : Delete file from the bucket using url
-func (adapter *AliyunAdapter) DeleteFileUsingURL(bucket, fileURL string) error {
-	storageClient, err := adapter.getClient()
+// DeleteMany : Delete the keys with DeleteObjects, in batches of 1000. A failed
+// batch fails each of its keys
+func (adapter *AliyunAdapter) DeleteMany(ctx context.Context, bucket string, keys []string) error {
+	storageClient, err := adapter.getClient(ctx)
 	if err != nil {
-		return err
+		return aliyunError("delete", bucket, "", err)
 	}
 
 	object, err := storageClient.Bucket(bucket)
 	if err != nil {
-		return err
+		return aliyunError("delete", bucket, "", err)
+	}
+
+	failures := &DeleteError{Bucket: bucket}
+	for _, batch := range deleteBatches(keys) {
+		if err := ctx.Err(); err != nil {
+			for _, key := range batch {
+				failures.add(key, aliyunError("delete", bucket, key, err))
+			}
+			continue
+		}
+
+		result, err := object.DeleteObjects(batch)
+		if err != nil {
+			for _, key := range batch {
+				failures.add(key, aliyunError("delete", bucket, key, err))
+			}
+			continue
+		}
+
+		deleted := make(map[string]bool, len(result.DeletedObjects))
+		for _, key := range result.DeletedObjects {
+			deleted[key] = true
+		}
+		for _, key := range batch {
+			if !deleted[key] {
+				failures.add(key, aliyunError("delete", bucket, key, errors.New("storage: the object was not deleted")))
+			}
+		}
+	}
+
+	return failures.errorOrNil()
+}
+
+// Copy : Copy with CopyObject, or CopyObjectTo between buckets. The overrides
+// replace every header of the object, so the others are carried over from the source
+func (adapter *AliyunAdapter) Copy(ctx context.Context, src, dst ObjectRef, options CopyOptions) (*UploadResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, aliyunError("copy", src.Bucket, src.Key, err)
+	}
+
+	storageClient, err := adapter.getClient(ctx)
+	if err != nil {
+		return nil, aliyunError("copy", src.Bucket, src.Key, err)
+	}
+
+	object, err := storageClient.Bucket(src.Bucket)
+	if err != nil {
+		return nil, aliyunError("copy", src.Bucket, src.Key, err)
+	}
+
+	copyOptions := make([]oss.Option, 0)
+	if !options.isZero() {
+		source, err := object.GetObjectDetailedMeta(src.Key)
+		if err != nil {
+			return nil, aliyunError("copy", src.Bucket, src.Key, err)
+		}
+
+		copyOptions, err = aliyunHeaderOptions(copyHeaders(source, oss.HTTPHeaderOssMetaPrefix, options))
+		if err != nil {
+			return nil, aliyunError("copy", src.Bucket, src.Key, err)
+		}
+		copyOptions = append(copyOptions, oss.MetadataDirective(oss.MetaReplace))
+	}
+
+	if src.Bucket == dst.Bucket {
+		_, err = object.CopyObject(src.Key, dst.Key, copyOptions...)
+	} else {
+		_, err = object.CopyObjectTo(dst.Bucket, dst.Key, src.Key, copyOptions...)
+	}
+	if err != nil {
+		return nil, aliyunError("copy", src.Bucket, src.Key, err)
 	}
 
-	filepath := adapter.getFilePathFromURL(bucket, fileURL)
+	info, err := adapter.Stat(ctx, dst.Bucket, dst.Key)
+	if err != nil {
+		return nil, err
+	}
 
-	return object.DeleteObject(filepath)
+	return copyResult(adapter.uploadResult(dst.Bucket, dst.Key, info.Size, info.ContentType), info), nil
 }
 
 // TemporaryServingFile : TemporaryServingFile file serving
 func (adapter *AliyunAdapter) TemporaryServingFile(bucket, fileURL string, expiredDateTime time.Time, aliClient interface{}) (string, error) {
-	storageClient, err := adapter.getClient()
+	return adapter.TemporaryServingFileWithContext(context.Background(), bucket, fileURL, expiredDateTime, aliClient)
+}
+
+// TemporaryServingFileWithContext : TemporaryServingFile file serving
+func (adapter *AliyunAdapter) TemporaryServingFileWithContext(ctx context.Context, bucket, fileURL string, expiredDateTime time.Time, aliClient interface{}) (string, error) {
+	key, err := objectKeyFromURL(bucket, fileURL)
 	if err != nil {
-		return "", err
+		return "", aliyunError("sign", bucket, fileURL, err)
+	}
+
+	return adapter.TemporaryServingObject(ctx, bucket, key, expiredDateTime, aliClient)
+}
+
+// TemporaryServingObject : Sign the object by its key, the url is signed locally so
+// the context is only checked before signing
+func (adapter *AliyunAdapter) TemporaryServingObject(ctx context.Context, bucket, key string, expiredDateTime time.Time, aliClient interface{}) (string, error) {
+	return adapter.TemporaryServingObjectWithOptions(ctx, bucket, key, expiredDateTime, aliClient, ResponseOptions{})
+}
+
+// TemporaryServingObjectWithOptions : Sign the object by its key, with the response
+// overrides signed as response-* parameters
+func (adapter *AliyunAdapter) TemporaryServingObjectWithOptions(ctx context.Context, bucket, key string, expiredDateTime time.Time, aliClient interface{}, options ResponseOptions) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", aliyunError("sign", bucket, key, err)
+	}
+
+	storageClient, err := adapter.getClient(ctx)
+	if err != nil {
+		return "", aliyunError("sign", bucket, key, err)
 	}
 
 	object, err := storageClient.Bucket(bucket)
 	if err != nil {
-		return "", err
+		return "", aliyunError("sign", bucket, key, err)
 	}
 
-	filepath := adapter.getFilePathFromURL(bucket, fileURL)
+	signOptions := make([]oss.Option, 0)
+	if options.ContentDisposition != "" {
+		signOptions = append(signOptions, oss.ResponseContentDisposition(options.ContentDisposition))
+	}
+	if options.ContentType != "" {
+		signOptions = append(signOptions, oss.ResponseContentType(options.ContentType))
+	}
+	if options.CacheControl != "" {
+		signOptions = append(signOptions, oss.ResponseCacheControl(options.CacheControl))
+	}
 
-	url, err := object.SignURL(filepath, http.MethodGet, int64(expiredDateTime.UTC().Sub(time.Now().UTC()).Seconds()))
+	url, err := object.SignURL(key, http.MethodGet, int64(expiredDateTime.UTC().Sub(time.Now().UTC()).Seconds()), signOptions...)
 	if err != nil {
-		return "", err
+		return "", aliyunError("sign", bucket, key, err)
 	}
 
 	return url, nil
 }
 
+// SignedUploadURL : Sign a PUT url of the object, the content type, md5 and
+// x-oss-* headers are signed. OSS can only enforce a max size with a post policy
+func (adapter *AliyunAdapter) SignedUploadURL(ctx context.Context, bucket, key string, options SignOptions) (*SignedRequest, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, aliyunError("sign", bucket, key, err)
+	}
+
+	if options.MaxSize > 0 {
+		return nil, aliyunError("sign", bucket, key, errMaxSizeNotSupported("aliyun"))
+	}
+
+	header := options.uploadHeaders()
+	signOptions, err := aliyunHeaderOptions(header)
+	if err != nil {
+		return nil, aliyunError("sign", bucket, key, err)
+	}
+
+	storageClient, err := adapter.getClient(ctx)
+	if err != nil {
+		return nil, aliyunError("sign", bucket, key, err)
+	}
+
+	object, err := storageClient.Bucket(bucket)
+	if err != nil {
+		return nil, aliyunError("sign", bucket, key, err)
+	}
+
+	url, err := object.SignURL(key, oss.HTTPPut, int64(options.Expires.UTC().Sub(time.Now().UTC()).Seconds()), signOptions...)
+	if err != nil {
+		return nil, aliyunError("sign", bucket, key, err)
+	}
+
+	// the sdk signs the security token of the client in the query
+	return &SignedRequest{Method: http.MethodPut, URL: url, Headers: header}, nil
+}
+
+// aliyunHeaderOptions : The sdk only sends the headers it has an option for, any
+// other x-oss-* header would be lost or fail the signature so it is refused
+func aliyunHeaderOptions(header http.Header) ([]oss.Option, error) {
+	options := make([]oss.Option, 0, len(header))
+	for key := range header {
+		value := header.Get(key)
+		name := strings.ToLower(key)
+		switch {
+		case name == "content-type":
+			options = append(options, oss.ContentType(value))
+		case name == "content-md5":
+			options = append(options, oss.ContentMD5(value))
+		case name == "content-disposition":
+			options = append(options, oss.ContentDisposition(value))
+		case name == "content-encoding":
+			options = append(options, oss.ContentEncoding(value))
+		case name == "content-language":
+			options = append(options, oss.ContentLanguage(value))
+		case name == "cache-control":
+			options = append(options, oss.CacheControl(value))
+		case strings.HasPrefix(name, "x-oss-meta-"):
+			options = append(options, oss.Meta(strings.TrimPrefix(name, "x-oss-meta-"), value))
+		case name == "x-oss-object-acl":
+			options = append(options, oss.ObjectACL(oss.ACLType(value)))
+		case name == "x-oss-storage-class":
+			options = append(options, oss.ObjectStorageClass(oss.StorageClassType(value)))
+		case strings.HasPrefix(name, "x-oss-"):
+			return nil, fmt.Errorf("%w: aliyun can't sign the %s header", ErrUnsupported, key)
+		}
+	}
+
+	return options, nil
+}
+
+// PostPolicy : Sign a policy of a PostObject form upload, with the credentials of
+// the client so a refreshed security token is used
+func (adapter *AliyunAdapter) PostPolicy(ctx context.Context, bucket, key string, options PolicyOptions) (*PostForm, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, aliyunError("sign", bucket, key, err)
+	}
+
+	storageClient, err := adapter.getClient(ctx)
+	if err != nil {
+		return nil, aliyunError("sign", bucket, key, err)
+	}
+	config := storageClient.Config
+
+	policy := newPostPolicy(bucket, key, options)
+	document, err := policy.encode("2006-01-02T15:04:05.000Z")
+	if err != nil {
+		return nil, aliyunError("sign", bucket, key, err)
+	}
+
+	mac := hmac.New(sha1.New, []byte(config.AccessKeySecret))
+	mac.Write([]byte(document))
+
+	policy.fields["OSSAccessKeyId"] = config.AccessKeyID
+	policy.fields["policy"] = document
+	policy.fields["Signature"] = base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	if config.SecurityToken != "" {
+		policy.fields["x-oss-security-token"] = config.SecurityToken
+	}
+
+	return &PostForm{URL: getAliyunFileURL(adapter.Endpoint, bucket, ""), Fields: policy.fields}, nil
+}
+
 // UploadReader :
 func (adapter *AliyunAdapter) UploadReader(bucket, filename string, reader io.Reader, contentType string) (string, error) {
-	storageClient, err := adapter.getClient()
+	return adapter.UploadReaderWithContext(context.Background(), bucket, filename, reader, contentType)
+}
+
+// UploadReaderWithContext :
+func (adapter *AliyunAdapter) UploadReaderWithContext(ctx context.Context, bucket, filename string, reader io.Reader, contentType string) (string, error) {
+	result, err := adapter.Put(ctx, bucket, filename, reader, contentType)
 	if err != nil {
 		return "", err
 	}
 
+	return result.URL, nil
+}
+
+// Put : Upload the reader and return the canonical identifiers of the object
+func (adapter *AliyunAdapter) Put(ctx context.Context, bucket, filename string, reader io.Reader, contentType string) (*UploadResult, error) {
+	return adapter.PutWithAttributes(ctx, bucket, filename, reader, contentType, ObjectAttributes{})
+}
+
+// PutWithAttributes : Same as Put, the metadata are set as x-oss-meta-* and the
+// tags as x-oss-tagging
+func (adapter *AliyunAdapter) PutWithAttributes(ctx context.Context, bucket, filename string, reader io.Reader, contentType string, attrs ObjectAttributes) (*UploadResult, error) {
+	return adapter.Upload(ctx, bucket, filename, reader, UploadOptions{ContentType: contentType, Attributes: attrs})
+}
+
+// Upload : The options are translated to the oss.Option of the put, IfNotExists
+// is sent as x-oss-forbid-overwrite
+func (adapter *AliyunAdapter) Upload(ctx context.Context, bucket, filename string, reader io.Reader, uploadOptions UploadOptions) (*UploadResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, aliyunError("upload", bucket, filename, err)
+	}
+
+	storageClient, err := adapter.getClient(ctx)
+	if err != nil {
+		return nil, aliyunError("upload", bucket, filename, err)
+	}
+
 	object, err := storageClient.Bucket(bucket)
 	if err != nil {
-		return "", err
+		return nil, aliyunError("upload", bucket, filename, err)
 	}
 
+	var head []byte
 	options := make([]oss.Option, 0)
-	if contentType == "" {
-		options = aliyunContentTypeAny(filename)
+	if uploadOptions.ContentType == "" {
+		if head, reader, err = peekContent(reader); err != nil {
+			msg := fmt.Errorf("Could not read file: %w", err)
+			return nil, aliyunError("upload", bucket, filename, msg)
+		}
+		options = aliyunContentTypeAny(filename, head)
 	} else {
-		contentFunc, isExist := aliyunContentTypeMapper[contentType]
+		contentFunc, isExist := aliyunContentTypeMapper[uploadOptions.ContentType]
 		if !isExist {
-			return "", fmt.Errorf("Content type %s does not supported", contentType)
+			return nil, aliyunError("upload", bucket, filename, errUnsupportedContentType(uploadOptions.ContentType))
 		}
 		options = contentFunc(filename)
 	}
 
-	if err := object.PutObject(filename, reader, options...); err != nil {
-		msg := fmt.Sprintf("Could not write file: %v", err)
-		return "", errors.New(msg)
+	// the later options replace the headers of the content type
+	header := uploadOptions.Attributes.header(oss.HTTPHeaderOssMetaPrefix)
+	if uploadOptions.ContentDisposition != "" {
+		header.Set(oss.HTTPHeaderContentDisposition, uploadOptions.ContentDisposition)
+	}
+	if uploadOptions.MD5 != nil {
+		header.Set(oss.HTTPHeaderContentMD5, base64.StdEncoding.EncodeToString(uploadOptions.MD5))
+	}
+	if uploadOptions.ACL != "" {
+		header.Set(oss.HTTPHeaderOssObjectACL, uploadOptions.ACL)
+	}
+	if uploadOptions.StorageClass != "" {
+		header.Set(oss.HTTPHeaderOssStorageClass, uploadOptions.StorageClass)
+	}
+
+	headerOptions, err := aliyunHeaderOptions(header)
+	if err != nil {
+		return nil, aliyunError("upload", bucket, filename, err)
+	}
+	options = append(options, headerOptions...)
+	if len(uploadOptions.Attributes.Tags) > 0 {
+		options = append(options, oss.SetHeader("x-oss-tagging", tagQuery(uploadOptions.Attributes.Tags)))
+	}
+	if uploadOptions.IfNotExists {
+		options = append(options, oss.SetHeader("x-oss-forbid-overwrite", "true"))
 	}
 
-	return getAliyunFileURL(adapter.Endpoint, bucket, filename), nil
+	// the sdk has no context support, so the upload is aborted by failing the next read
+	hr := newHashReader(newContextReader(ctx, reader))
+	resp, err := object.DoPutObject(&oss.PutObjectRequest{ObjectKey: filename, Reader: hr}, options)
+	if err != nil {
+		msg := fmt.Errorf("Could not write file: %w", err)
+		return nil, aliyunError("upload", bucket, filename, msg)
+	}
+	defer resp.Body.Close()
+
+	// the options can't be read back, the gcs mapper resolves the same content type
+	contentType, _, _ := resolveContentType(filename, uploadOptions.ContentType, head)
+
+	result := adapter.uploadResult(bucket, filename, hr.size, contentType)
+	result.ETag = strings.Trim(resp.Headers.Get(oss.HTTPHeaderEtag), `"`)
+	result.MD5 = hr.md5.Sum(nil)
+	result.CRC32C = hr.crc32c.Sum32()
+	result.VersionID = resp.Headers.Get("X-Oss-Version-Id")
+	return result, nil
 }
 
 // ReadFile :
 func (adapter *AliyunAdapter) ReadFile(bucket, path string) ([]byte, error) {
-	storageClient, err := adapter.getClient()
+	return adapter.ReadFileWithContext(context.Background(), bucket, path)
+}
+
+// ReadFileWithContext : Convenience wrapper over OpenReader, the whole object is kept in memory
+func (adapter *AliyunAdapter) ReadFileWithContext(ctx context.Context, bucket, path string) ([]byte, error) {
+	rc, err := adapter.OpenRangeReader(ctx, bucket, path, 0, -1)
 	if err != nil {
 		return nil, err
 	}
+	defer rc.Close()
 
-	object, err := storageClient.Bucket(bucket)
+	return ioutil.ReadAll(rc)
+}
+
+// OpenRangeReader : Read length bytes from offset through oss.Range, a negative
+// length reads until the end of the object
+func (adapter *AliyunAdapter) OpenRangeReader(ctx context.Context, bucket, path string, offset, length int64) (io.ReadCloser, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, aliyunError("read", bucket, path, err)
+	}
+
+	storageClient, err := adapter.getClient(ctx)
 	if err != nil {
-		return nil, err
+		return nil, aliyunError("read", bucket, path, err)
 	}
 
-	rc, err := object.GetObject(path)
+	object, err := storageClient.Bucket(bucket)
 	if err != nil {
-		return nil, err
+		return nil, aliyunError("read", bucket, path, err)
 	}
 
-	defer rc.Close()
-	slurp, err := ioutil.ReadAll(rc)
+	options := make([]oss.Option, 0)
+	if length == 0 {
+		return ioutil.NopCloser(bytes.NewReader(nil)), nil
+	} else if length > 0 {
+		options = append(options, oss.Range(offset, offset+length-1))
+	} else if offset > 0 {
+		options = append(options, oss.NormalizedRange(fmt.Sprintf("%d-", offset)))
+	}
+
+	result, err := object.DoGetObject(&oss.GetObjectRequest{ObjectKey: path}, options)
 	if err != nil {
-		return nil, err
+		// reading past the end of the object returns nothing, same as the other adapters
+		var e oss.ServiceError
+		if errors.As(err, &e) && e.Code == "InvalidRange" {
+			return ioutil.NopCloser(bytes.NewReader(nil)), nil
+		}
+		return nil, aliyunError("read", bucket, path, err)
 	}
-	return slurp, nil
+	rc := result.Response.Body
+
+	// the sdk can't send x-oss-range-behavior: standard, so oss ignores a range running
+	// past the end of the object and answers 200 with the whole object. The range is
+	// applied here instead
+	var reader io.Reader = rc
+	if len(options) > 0 && result.Response.StatusCode == http.StatusOK {
+		size, _ := strconv.ParseInt(result.Response.Headers.Get(oss.HTTPHeaderContentLength), 10, 64)
+		if offset >= size {
+			rc.Close()
+			return ioutil.NopCloser(bytes.NewReader(nil)), nil
+		}
+
+		if _, err := io.CopyN(ioutil.Discard, rc, offset); err != nil {
+			rc.Close()
+			return nil, aliyunError("read", bucket, path, err)
+		}
+		if length > 0 {
+			reader = io.LimitReader(rc, length)
+		}
+	}
+
+	return &readCloser{Reader: newContextReader(ctx, reader), Closer: rc}, nil
 }
 
 // UploadBuffer :
 func (adapter *AliyunAdapter) UploadBuffer(bucket, filename string, contentType string) (*Buffer, error) {
+	return adapter.UploadBufferWithContext(context.Background(), bucket, filename, contentType)
+}
+
+// UploadBufferWithContext :
+func (adapter *AliyunAdapter) UploadBufferWithContext(ctx context.Context, bucket, filename string, contentType string) (*Buffer, error) {
 	buf := new(Buffer)
 	buf.adapter = ALIYUN
+	buf.ctx = ctx
+
+	if err := ctx.Err(); err != nil {
+		return nil, aliyunError("upload", bucket, filename, err)
+	}
 
-	storageClient, err := adapter.getClient()
+	storageClient, err := adapter.getClient(ctx)
 	if err != nil {
-		return nil, err
+		return nil, aliyunError("upload", bucket, filename, err)
 	}
 
 	object, err := storageClient.Bucket(bucket)
 	if err != nil {
-		return nil, err
+		return nil, aliyunError("upload", bucket, filename, err)
 	}
 
-	contentFunc, isExist := aliyunContentTypeMapper[contentType]
-	if !isExist {
-		return nil, fmt.Errorf("Content type %s does not supported", contentType)
+	options := make([]oss.Option, 0)
+	if contentType == "" {
+		options = aliyunContentTypeAny(filename, nil)
+	} else {
+		contentFunc, isExist := aliyunContentTypeMapper[contentType]
+		if !isExist {
+			return nil, aliyunError("upload", bucket, filename, errUnsupportedContentType(contentType))
+		}
+		options = contentFunc(filename)
 	}
-	options := contentFunc(filename)
 
 	buffer := new(bytes.Buffer)
 
@@ -175,30 +626,252 @@ func (adapter *AliyunAdapter) UploadBuffer(bucket, filename string, contentType
 
 	position, err := object.AppendObject(filename, buffer, buf.position, options...)
 	if err != nil {
-		log.Println("error: ", err)
-		return nil, err
+		return nil, aliyunError("upload", bucket, filename, err)
 	}
-	buf.position = position
 
 	buf.position = position
-	buf.object = object
 	buf.filename = filename
 	buf.bucket = bucket
-	buf.endpoint = adapter.Endpoint
+	buf.contentType, _, _ = resolveContentType(filename, contentType, nil)
+	buf.aliyun = adapter
 	return buf, nil
 }
 
-func (adapter *AliyunAdapter) getClient() (*oss.Client, error) {
-	return oss.New(adapter.Endpoint, adapter.AccessKeyID, adapter.AccessKeySecret)
+// List : Return one page of the objects through ListObjects, the page token is the marker.
+// Aliyun doesn't return the content type when listing
+func (adapter *AliyunAdapter) List(ctx context.Context, bucket string, options ListOptions) (*ListResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, aliyunError("list", bucket, options.Prefix, err)
+	}
+
+	storageClient, err := adapter.getClient(ctx)
+	if err != nil {
+		return nil, aliyunError("list", bucket, options.Prefix, err)
+	}
+
+	object, err := storageClient.Bucket(bucket)
+	if err != nil {
+		return nil, aliyunError("list", bucket, options.Prefix, err)
+	}
+
+	pageSize := options.PageSize
+	if pageSize <= 0 {
+		pageSize = defaultListPageSize
+	}
+
+	listOptions := []oss.Option{oss.MaxKeys(pageSize)}
+	if options.Prefix != "" {
+		listOptions = append(listOptions, oss.Prefix(options.Prefix))
+	}
+	if options.Delimiter != "" {
+		listOptions = append(listOptions, oss.Delimiter(options.Delimiter))
+	}
+	if options.PageToken != "" {
+		listOptions = append(listOptions, oss.Marker(options.PageToken))
+	}
+
+	objects, err := object.ListObjects(listOptions...)
+	if err != nil {
+		return nil, aliyunError("list", bucket, options.Prefix, err)
+	}
+
+	result := new(ListResult)
+	if objects.IsTruncated {
+		result.NextPageToken = objects.NextMarker
+	}
+	for _, o := range objects.Objects {
+		result.Objects = append(result.Objects, &ObjectInfo{
+			Name:    o.Key,
+			Size:    o.Size,
+			Updated: o.LastModified,
+		})
+	}
+	for _, prefix := range objects.CommonPrefixes {
+		result.Objects = append(result.Objects, &ObjectInfo{Prefix: prefix})
+	}
+
+	return result, nil
+}
+
+// Stat : Return the object attributes through GetObjectDetailedMeta. Aliyun has no
+// creation time nor crc32c, and only returns the md5 when it was given on upload
+func (adapter *AliyunAdapter) Stat(ctx context.Context, bucket, filename string) (*ObjectInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, aliyunError("stat", bucket, filename, err)
+	}
+
+	storageClient, err := adapter.getClient(ctx)
+	if err != nil {
+		return nil, aliyunError("stat", bucket, filename, err)
+	}
+
+	object, err := storageClient.Bucket(bucket)
+	if err != nil {
+		return nil, aliyunError("stat", bucket, filename, err)
+	}
+
+	header, err := object.GetObjectDetailedMeta(filename)
+	if err != nil {
+		return nil, aliyunError("stat", bucket, filename, err)
+	}
+
+	info := new(ObjectInfo)
+	info.Name = filename
+	info.Size, _ = strconv.ParseInt(header.Get(oss.HTTPHeaderContentLength), 10, 64)
+	info.ContentType = header.Get(oss.HTTPHeaderContentType)
+	info.ContentDisposition = header.Get(oss.HTTPHeaderContentDisposition)
+	info.ContentEncoding = header.Get(oss.HTTPHeaderContentEncoding)
+	info.ContentLanguage = header.Get(oss.HTTPHeaderContentLanguage)
+	info.CacheControl = header.Get(oss.HTTPHeaderCacheControl)
+	info.ETag = strings.Trim(header.Get(oss.HTTPHeaderEtag), `"`)
+	info.MD5, _ = base64.StdEncoding.DecodeString(header.Get(oss.HTTPHeaderContentMD5))
+	info.Updated, _ = http.ParseTime(header.Get(oss.HTTPHeaderLastModified))
+	info.Metadata = make(map[string]string)
+	for key := range header {
+		if strings.HasPrefix(strings.ToLower(key), strings.ToLower(oss.HTTPHeaderOssMetaPrefix)) {
+			name := strings.ToLower(key[len(oss.HTTPHeaderOssMetaPrefix):])
+			info.Metadata[name] = header.Get(key)
+		}
+	}
+
+	return info, nil
+}
+
+func (adapter *AliyunAdapter) uploadResult(bucket, filename string, size int64, contentType string) *UploadResult {
+	// without a content type the sdk sends the one of the extension
+	if contentType == "" {
+		contentType = oss.TypeByExtension(filename)
+	}
+
+	return &UploadResult{
+		Bucket:      bucket,
+		Key:         filename,
+		URL:         getAliyunFileURL(adapter.Endpoint, bucket, filename),
+		Size:        size,
+		ContentType: contentType,
+	}
+}
+
+// Close : Release the oss client, the adapter creates a new one if it is used again.
+// The sdk keeps its transport private, so the idle connections are left to time out
+func (adapter *AliyunAdapter) Close() error {
+	adapter.mu.Lock()
+	defer adapter.mu.Unlock()
+
+	adapter.client = nil
+	adapter.expiration = time.Time{}
+	adapter.refreshAt = time.Time{}
+	adapter.refreshErr = nil
+	return nil
+}
+
+// getClient : Every operation gets the client again, so the client rebuilt with
+// the refreshed credentials is picked up by the next operation. The provider is
+// called outside of the lock, the operations keep the current client meanwhile
+// and the ones without a usable client wait for the refresh or their ctx
+func (adapter *AliyunAdapter) getClient(ctx context.Context) (*oss.Client, error) {
+	for {
+		adapter.mu.Lock()
+		now := time.Now()
+		usable := adapter.client != nil && (adapter.expiration.IsZero() || now.Before(adapter.expiration))
+		if usable && (adapter.expiration.IsZero() || now.Before(adapter.refreshAt)) {
+			client := adapter.client
+			adapter.mu.Unlock()
+			return client, nil
+		}
+
+		if refreshing := adapter.refreshing; refreshing != nil {
+			client := adapter.client
+			adapter.mu.Unlock()
+			if usable {
+				return client, nil
+			}
+
+			select {
+			case <-refreshing:
+				continue
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+
+		// the last refresh failed, the provider is not called again before refreshAt
+		if adapter.refreshErr != nil && now.Before(adapter.refreshAt) {
+			err := adapter.refreshErr
+			adapter.mu.Unlock()
+			return nil, err
+		}
+
+		refreshing := make(chan struct{})
+		adapter.refreshing = refreshing
+		adapter.mu.Unlock()
+
+		client, expiration, err := adapter.newClient(ctx)
+
+		adapter.mu.Lock()
+		adapter.refreshing = nil
+		close(refreshing)
+		now = time.Now()
+		if err != nil {
+			// a cancelled caller doesn't hold back the refresh of the others
+			if ctx.Err() == nil {
+				adapter.refreshErr = err
+				adapter.refreshAt = now.Add(aliyunRefreshInterval)
+			}
+			client, usable = adapter.client, adapter.client != nil && now.Before(adapter.expiration)
+			adapter.mu.Unlock()
+
+			// keep the current client until its credentials actually expire
+			if usable {
+				return client, nil
+			}
+			return nil, err
+		}
+
+		adapter.client = client
+		adapter.expiration = expiration
+		adapter.refreshAt = nextAliyunRefresh(now, expiration)
+		adapter.refreshErr = nil
+		adapter.mu.Unlock()
+
+		return client, nil
+	}
+}
+
+// newClient : Build a client with the current credentials
+func (adapter *AliyunAdapter) newClient(ctx context.Context) (*oss.Client, time.Time, error) {
+	credentials, err := adapter.credentials(ctx)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("%w: could not get aliyun credentials: %v", ErrInvalidClient, err)
+	}
+
+	options := make([]oss.ClientOption, 0)
+	if credentials.SecurityToken != "" {
+		options = append(options, oss.SecurityToken(credentials.SecurityToken))
+	}
+
+	storageClient, err := oss.New(adapter.Endpoint, credentials.AccessKeyID, credentials.AccessKeySecret, options...)
+	if err != nil {
+		return nil, time.Time{}, fmt.Errorf("%w: %v", ErrInvalidClient, err)
+	}
+
+	return storageClient, credentials.Expiration, nil
 }
 
 func getAliyunFileURL(endpoint, bucket string, filename string) string {
 	endpoint = regexp.MustCompile(`^(http|https)://`).ReplaceAllString(endpoint, "")
-	return fmt.Sprintf("https://%s.%s/%s", bucket, endpoint, filename)
+	return fmt.Sprintf("https://%s.%s/%s", bucket, endpoint, escapeObjectKey(filename))
 }
 
-func (adapter *AliyunAdapter) getFilePathFromURL(bucket, fileURL string) string {
-	endpoint := regexp.MustCompile(`^(http|https)://`).ReplaceAllString(adapter.Endpoint, "")
-	f := regexp.MustCompile(`^(http|https)://`).ReplaceAllString(fileURL, "")
-	return strings.Replace(f, fmt.Sprintf("%s.%s/", bucket, endpoint), "", -1)
+// aliyunBucketPattern : 3 to 63 lowercase letters, digits and dashes, starting and
+// ending with a letter or digit
+var aliyunBucketPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,61}[a-z0-9]$`)
+
+// validateBucket : The aliyun names have neither dots nor underscores
+func (adapter *AliyunAdapter) validateBucket(bucket string) error {
+	if !aliyunBucketPattern.MatchString(bucket) {
+		return errInvalidBucketName(bucket)
+	}
+
+	return nil
 }
@@ -2,7 +2,6 @@ package storage
 
 import (
 	"fmt"
-	"strings"
 
 	"github.com/aliyun/aliyun-oss-go-sdk/oss"
 )
@@ -67,17 +66,17 @@ func aliyunContentTypePDF(filename string) []oss.Option {
 	return options
 }
 
-func aliyunContentTypeAny(filename string) []oss.Option {
-	filenameArr := strings.Split(filename, ".")
-	fileExtension := strings.TrimSpace(filenameArr[len(filenameArr)-1])
-
+// aliyunContentTypeAny : The options of the content type detected from the head of
+// the data, or of the extension of the name when the data has no signature
+func aliyunContentTypeAny(filename string, head []byte) []oss.Option {
 	options := make([]oss.Option, 0)
 	// options = append(options, oss.ContentDisposition(fmt.Sprintf("attachment;filename=%s", filename)))
 
-	contentFunc, isExist := aliyunContentTypeMapper[fileExtension]
-	if isExist {
-		o := contentFunc(filename)
-		options = append(options, o...)
+	key, contentType := sniffContentType(filename, head)
+	if contentFunc, isExist := aliyunContentTypeMapper[key]; isExist {
+		options = append(options, contentFunc(filename)...)
+	} else if contentType != "" {
+		options = append(options, oss.ContentType(contentType))
 	}
 
 	return options
@@ -114,7 +113,7 @@ func aliyunContentTypeJS(filename string) []oss.Option {
 
 func aliyunContentTypeExcel(filename string) []oss.Option {
 	options := make([]oss.Option, 0)
-	options = append(options, oss.ContentType("application/vnd.ms-excel"))
+	options = append(options, oss.ContentType("application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"))
 	options = append(options, oss.ContentDisposition(fmt.Sprintf("attachment;filename=%s", filename)))
 
 	return options
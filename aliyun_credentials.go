@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+const (
+	// aliyunRefreshWindow : The credentials are refreshed this long before they
+	// expire, so the requests in flight are not signed with an expired token
+	aliyunRefreshWindow = 5 * time.Minute
+
+	// aliyunRefreshInterval : The provider is not called more often, when it
+	// fails or returns credentials already within the refresh window
+	aliyunRefreshInterval = 30 * time.Second
+)
+
+// AliyunCredentials : Expiration is zero for the long term access keys
+type AliyunCredentials struct {
+	AccessKeyID     string
+	AccessKeySecret string
+	SecurityToken   string
+	Expiration      time.Time
+}
+
+// AliyunCredentialsProvider : Return the current credentials, e.g. from an STS
+// AssumeRole call. It is called again shortly before they expire
+type AliyunCredentialsProvider interface {
+	Credentials(ctx context.Context) (*AliyunCredentials, error)
+}
+
+// AliyunCredentialsProviderFunc : Use an ordinary function as a provider
+type AliyunCredentialsProviderFunc func(ctx context.Context) (*AliyunCredentials, error)
+
+// Credentials :
+func (f AliyunCredentialsProviderFunc) Credentials(ctx context.Context) (*AliyunCredentials, error) {
+	return f(ctx)
+}
+
+// credentials : The credentials of the provider, or the static ones of the adapter
+func (adapter *AliyunAdapter) credentials(ctx context.Context) (*AliyunCredentials, error) {
+	if adapter.CredentialsProvider == nil {
+		return &AliyunCredentials{
+			AccessKeyID:     adapter.AccessKeyID,
+			AccessKeySecret: adapter.AccessKeySecret,
+			SecurityToken:   adapter.SecurityToken,
+		}, nil
+	}
+
+	credentials, err := adapter.CredentialsProvider.Credentials(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if credentials == nil {
+		return nil, errors.New("the provider returned no credentials")
+	}
+	if !credentials.Expiration.IsZero() && !time.Now().Before(credentials.Expiration) {
+		return nil, errors.New("the provider returned expired credentials")
+	}
+
+	return credentials, nil
+}
+
+// nextAliyunRefresh : The refresh window before the expiration, but not sooner than
+// aliyunRefreshInterval. The long term access keys are never refreshed
+func nextAliyunRefresh(now, expiration time.Time) time.Time {
+	if expiration.IsZero() {
+		return time.Time{}
+	}
+
+	refreshAt := expiration.Add(-aliyunRefreshWindow)
+	if min := now.Add(aliyunRefreshInterval); refreshAt.Before(min) {
+		refreshAt = min
+	}
+	return refreshAt
+}
@@ -0,0 +1,191 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAliyunStaticCredentials(t *testing.T) {
+	adapter := &AliyunAdapter{
+		Endpoint:        "oss-cn-hangzhou.aliyuncs.com",
+		AccessKeyID:     "id",
+		AccessKeySecret: "secret",
+		SecurityToken:   "token",
+	}
+
+	client, err := adapter.getClient(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client.Config.AccessKeyID != "id" || client.Config.SecurityToken != "token" {
+		t.Errorf("unexpected client config %+v", client.Config)
+	}
+	if again, _ := adapter.getClient(context.Background()); again != client {
+		t.Error("expected the client of the long term keys to be reused")
+	}
+}
+
+func TestAliyunCredentialsProvider(t *testing.T) {
+	calls := 0
+	expiration := time.Now().Add(time.Hour)
+	adapter := &AliyunAdapter{
+		Endpoint: "oss-cn-hangzhou.aliyuncs.com",
+		CredentialsProvider: AliyunCredentialsProviderFunc(func(ctx context.Context) (*AliyunCredentials, error) {
+			calls++
+			return &AliyunCredentials{AccessKeyID: "sts", AccessKeySecret: "secret", SecurityToken: "token", Expiration: expiration}, nil
+		}),
+	}
+
+	first, err := adapter.getClient(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Config.AccessKeyID != "sts" || first.Config.SecurityToken != "token" {
+		t.Errorf("unexpected client config %+v", first.Config)
+	}
+	if second, _ := adapter.getClient(context.Background()); second != first || calls != 1 {
+		t.Errorf("expected the client to be reused until the credentials expire, %d calls", calls)
+	}
+
+	// the credentials expire within the refresh window
+	expiration = time.Now().Add(time.Minute)
+	adapter.mu.Lock()
+	adapter.expiration = expiration
+	adapter.refreshAt = time.Now().Add(-time.Second)
+	adapter.mu.Unlock()
+
+	third, err := adapter.getClient(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if third == first || calls != 2 {
+		t.Errorf("expected the expiring credentials to be refreshed, %d calls", calls)
+	}
+}
+
+func newAliyunTestAdapter(provider AliyunCredentialsProviderFunc) *AliyunAdapter {
+	return &AliyunAdapter{Endpoint: "oss-cn-hangzhou.aliyuncs.com", CredentialsProvider: provider}
+}
+
+func TestAliyunNilCredentials(t *testing.T) {
+	adapter := newAliyunTestAdapter(func(ctx context.Context) (*AliyunCredentials, error) {
+		return nil, nil
+	})
+
+	if _, err := adapter.getClient(context.Background()); !errors.Is(err, ErrInvalidClient) {
+		t.Fatalf("expected ErrInvalidClient, got %v", err)
+	}
+}
+
+func TestAliyunShortLivedCredentialsAreNotRefreshedOnEveryCall(t *testing.T) {
+	var calls int32
+	adapter := newAliyunTestAdapter(func(ctx context.Context) (*AliyunCredentials, error) {
+		atomic.AddInt32(&calls, 1)
+		return &AliyunCredentials{AccessKeyID: "id", AccessKeySecret: "secret", Expiration: time.Now().Add(time.Minute)}, nil
+	})
+
+	for i := 0; i < 10; i++ {
+		if _, err := adapter.getClient(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if calls != 1 {
+		t.Errorf("expected the provider to be called once, got %d", calls)
+	}
+}
+
+func TestAliyunFailedRefreshKeepsTheClient(t *testing.T) {
+	fail := false
+	adapter := newAliyunTestAdapter(func(ctx context.Context) (*AliyunCredentials, error) {
+		if fail {
+			return nil, errors.New("sts is down")
+		}
+		return &AliyunCredentials{AccessKeyID: "id", AccessKeySecret: "secret", Expiration: time.Now().Add(time.Minute)}, nil
+	})
+
+	client, err := adapter.getClient(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fail = true
+	adapter.refreshAt = time.Now().Add(-time.Second)
+	got, err := adapter.getClient(context.Background())
+	if err != nil {
+		t.Fatalf("expected the current client while the credentials are valid, got %v", err)
+	}
+	if got != client {
+		t.Error("expected the current client")
+	}
+
+	// the provider is not called again before refreshAt, once the credentials expired
+	adapter.mu.Lock()
+	adapter.expiration = time.Now().Add(-time.Second)
+	adapter.mu.Unlock()
+	if _, err := adapter.getClient(context.Background()); !errors.Is(err, ErrInvalidClient) {
+		t.Errorf("expected ErrInvalidClient once the credentials expired, got %v", err)
+	}
+}
+
+func TestAliyunConcurrentRefresh(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	adapter := newAliyunTestAdapter(func(ctx context.Context) (*AliyunCredentials, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return &AliyunCredentials{AccessKeyID: "id", AccessKeySecret: "secret", Expiration: time.Now().Add(time.Hour)}, nil
+	})
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := adapter.getClient(context.Background())
+			errs <- err
+		}()
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("expected a single provider call, got %d", calls)
+	}
+}
+
+func TestAliyunRefreshHonoursTheContext(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	adapter := newAliyunTestAdapter(func(ctx context.Context) (*AliyunCredentials, error) {
+		select {
+		case <-release:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		return &AliyunCredentials{AccessKeyID: "id", AccessKeySecret: "secret"}, nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := adapter.getClient(ctx); !errors.Is(err, ErrInvalidClient) {
+		t.Fatalf("expected ErrInvalidClient, got %v", err)
+	}
+
+	if adapter.refreshErr != nil {
+		t.Errorf("a cancelled caller should not hold back the next refresh: %v", adapter.refreshErr)
+	}
+}
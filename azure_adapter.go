@@ -0,0 +1,797 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	azureScheme      = "azblob://"
+	azureStorageHost = ".blob.core.windows.net"
+	azureMetaPrefix  = "X-Ms-Meta-"
+
+	// azureCopyPollInterval : Copies within the account usually complete at once
+	azureCopyPollInterval = 500 * time.Millisecond
+)
+
+// AzureBlobAdapter : Store the objects as block blobs, the bucket is the container.
+// Endpoint defaults to the blob endpoint of the account, for the Azurite emulator
+// it is e.g. http://127.0.0.1:10000/devstoreaccount1
+type AzureBlobAdapter struct {
+	AccountName string
+	AccountKey  string
+	Endpoint    string
+	HTTPClient  *http.Client
+}
+
+func init() {
+	Register(AZURE, func(config Config) (Adapter, error) {
+		var client AzureClient
+		switch config := config.(type) {
+		case nil:
+			// the environment
+		case AzureClient:
+			client = config
+		case *AzureClient:
+			if config == nil {
+				return nil, invalidConfig(AZURE, config)
+			}
+			client = *config
+		default:
+			return nil, invalidConfig(AZURE, config)
+		}
+
+		adapter := new(AzureBlobAdapter)
+		adapter.AccountName = client.AccountName
+		adapter.AccountKey = client.AccountKey
+		adapter.Endpoint = client.Endpoint
+		adapter.HTTPClient = client.HTTPClient
+		if _, err := adapter.signer(); err != nil {
+			return nil, err
+		}
+		if _, err := adapter.endpoint(); err != nil {
+			return nil, err
+		}
+		return adapter, nil
+	})
+}
+
+// AzureServiceError : The error returned by the blob service, the head requests
+// have no body so the code comes from the x-ms-error-code header
+type AzureServiceError struct {
+	StatusCode int
+	Code       string `xml:"Code"`
+	Message    string `xml:"Message"`
+	RequestID  string
+}
+
+func (e *AzureServiceError) Error() string {
+	return fmt.Sprintf("azure: service returned error: StatusCode=%d, ErrorCode=%s, ErrorMessage=%q, RequestId=%s",
+		e.StatusCode, e.Code, e.Message, e.RequestID)
+}
+
+// UploadFile : Upload file to the container
+func (adapter *AzureBlobAdapter) UploadFile(file *multipart.FileHeader, container, filename string) (string, error) {
+	return adapter.UploadFileWithContext(context.Background(), file, container, filename)
+}
+
+// UploadFileWithContext : Upload file to the container
+func (adapter *AzureBlobAdapter) UploadFileWithContext(ctx context.Context, file *multipart.FileHeader, container, filename string) (string, error) {
+	result, err := uploadMultipartFile(ctx, adapter, file, container, filename)
+	if err != nil {
+		return "", err
+	}
+
+	return result.URL, nil
+}
+
+// DeleteFileUsingURL : Delete file from the container using url
+func (adapter *AzureBlobAdapter) DeleteFileUsingURL(container, fileURL string) error {
+	return adapter.DeleteFileUsingURLWithContext(context.Background(), container, fileURL)
+}
+
+// DeleteFileUsingURLWithContext : Delete file from the container using url
+func (adapter *AzureBlobAdapter) DeleteFileUsingURLWithContext(ctx context.Context, container, fileURL string) error {
+	key, err := adapter.objectKey(container, fileURL)
+	if err != nil {
+		return azureError("delete", container, fileURL, err)
+	}
+
+	return adapter.DeleteObject(ctx, container, key)
+}
+
+// DeleteObject : Delete the blob by its name
+func (adapter *AzureBlobAdapter) DeleteObject(ctx context.Context, container, key string) error {
+	resp, err := adapter.do(ctx, http.MethodDelete, container, key, nil, nil, nil)
+	// deleting a missing blob succeeds, same as the other providers
+	var e *AzureServiceError
+	if errors.As(err, &e) && e.Code == "BlobNotFound" {
+		return nil
+	}
+	if err != nil {
+		return azureError("delete", container, key, err)
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+// Copy : Copy with Copy Blob, waiting for the copy to complete. A content type
+// override is set afterwards with Set Blob Properties
+func (adapter *AzureBlobAdapter) Copy(ctx context.Context, src, dst ObjectRef, options CopyOptions) (*UploadResult, error) {
+	source, err := adapter.objectURL(src.Bucket, src.Key)
+	if err != nil {
+		return nil, azureError("copy", src.Bucket, src.Key, err)
+	}
+
+	header := make(http.Header)
+	header.Set("X-Ms-Copy-Source", source.String())
+	for key, value := range options.Metadata {
+		header.Set(azureMetaPrefix+key, value)
+	}
+
+	resp, err := adapter.do(ctx, http.MethodPut, dst.Bucket, dst.Key, nil, header, nil)
+	if err != nil {
+		return nil, azureError("copy", src.Bucket, src.Key, err)
+	}
+	resp.Body.Close()
+
+	if err := adapter.waitCopy(ctx, dst, resp.Header); err != nil {
+		return nil, azureError("copy", src.Bucket, src.Key, err)
+	}
+
+	if options.ContentType != "" {
+		changes := http.Header{"X-Ms-Blob-Content-Type": {options.ContentType}}
+		if err := adapter.setProperties(ctx, dst.Bucket, dst.Key, changes); err != nil {
+			return nil, azureError("copy", dst.Bucket, dst.Key, err)
+		}
+	}
+
+	info, err := adapter.Stat(ctx, dst.Bucket, dst.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	return copyResult(adapter.uploadResult(dst.Bucket, dst.Key, info.Size, nil), info), nil
+}
+
+// UpdateMetadata : Set Blob Metadata replaces the metadata, Set Blob Properties the
+// content headers and Set Blob Tags the tags
+func (adapter *AzureBlobAdapter) UpdateMetadata(ctx context.Context, container, key string, attrs ObjectAttributes) error {
+	if attrs.Metadata != nil {
+		header := make(http.Header)
+		for k, v := range attrs.Metadata {
+			header.Set(azureMetaPrefix+k, v)
+		}
+
+		resp, err := adapter.do(ctx, http.MethodPut, container, key, url.Values{"comp": {"metadata"}}, header, nil)
+		if err != nil {
+			return azureError("update", container, key, err)
+		}
+		resp.Body.Close()
+	}
+
+	changes := azureAttributesHeader(ObjectAttributes{
+		CacheControl:    attrs.CacheControl,
+		ContentEncoding: attrs.ContentEncoding,
+		ContentLanguage: attrs.ContentLanguage,
+	})
+	if len(changes) > 0 {
+		if err := adapter.setProperties(ctx, container, key, changes); err != nil {
+			return azureError("update", container, key, err)
+		}
+	}
+
+	if attrs.Tags != nil {
+		body, err := xml.Marshal(struct {
+			XMLName xml.Name `xml:"Tags"`
+			tagSet
+		}{tagSet: newTagSet(attrs.Tags)})
+		if err != nil {
+			return azureError("update", container, key, err)
+		}
+
+		header := http.Header{"Content-Type": {"application/xml"}}
+		resp, err := adapter.do(ctx, http.MethodPut, container, key, url.Values{"comp": {"tags"}}, header, append([]byte(xml.Header), body...))
+		if err != nil {
+			return azureError("update", container, key, err)
+		}
+		resp.Body.Close()
+	}
+
+	return nil
+}
+
+// setProperties : Set Blob Properties clears the content headers it isn't given, so
+// the current ones are sent again along with the changes
+func (adapter *AzureBlobAdapter) setProperties(ctx context.Context, container, key string, changes http.Header) error {
+	resp, err := adapter.do(ctx, http.MethodHead, container, key, nil, nil, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	header := make(http.Header)
+	for _, name := range []string{"Content-Type", "Content-Disposition", "Content-Encoding", "Content-Language", "Content-MD5", "Cache-Control"} {
+		if value := resp.Header.Get(name); value != "" {
+			header.Set("X-Ms-Blob-"+name, value)
+		}
+	}
+	for name, values := range changes {
+		header[name] = values
+	}
+
+	resp, err = adapter.do(ctx, http.MethodPut, container, key, url.Values{"comp": {"properties"}}, header, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+// waitCopy : Poll the destination until the copy is no longer pending
+func (adapter *AzureBlobAdapter) waitCopy(ctx context.Context, dst ObjectRef, header http.Header) error {
+	status := header.Get("X-Ms-Copy-Status")
+	for status == "pending" {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(azureCopyPollInterval):
+		}
+
+		resp, err := adapter.do(ctx, http.MethodHead, dst.Bucket, dst.Key, nil, nil, nil)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		header = resp.Header
+		status = header.Get("X-Ms-Copy-Status")
+	}
+
+	if status != "success" {
+		return fmt.Errorf("storage: azure copy %s: %s", status, header.Get("X-Ms-Copy-Status-Description"))
+	}
+
+	return nil
+}
+
+// TemporaryServingFile : Append a read only SAS token to the file url, which expires at expiredDateTime
+func (adapter *AzureBlobAdapter) TemporaryServingFile(container, fileURL string, expiredDateTime time.Time, azureClient interface{}) (string, error) {
+	return adapter.TemporaryServingFileWithContext(context.Background(), container, fileURL, expiredDateTime, azureClient)
+}
+
+// TemporaryServingFileWithContext : Append a read only SAS token to the file url, which expires at expiredDateTime
+func (adapter *AzureBlobAdapter) TemporaryServingFileWithContext(ctx context.Context, container, fileURL string, expiredDateTime time.Time, azureClient interface{}) (string, error) {
+	key, err := adapter.objectKey(container, fileURL)
+	if err != nil {
+		return "", azureError("sign", container, fileURL, err)
+	}
+
+	return adapter.TemporaryServingObject(ctx, container, key, expiredDateTime, azureClient)
+}
+
+// TemporaryServingObject : Append a read only service SAS token to the blob url, the
+// token is signed locally so the context is only checked before signing
+func (adapter *AzureBlobAdapter) TemporaryServingObject(ctx context.Context, container, key string, expiredDateTime time.Time, azureClient interface{}) (string, error) {
+	return adapter.TemporaryServingObjectWithOptions(ctx, container, key, expiredDateTime, azureClient, ResponseOptions{})
+}
+
+// TemporaryServingObjectWithOptions : Append a read only service SAS token, with the
+// response overrides signed in the rscc, rscd and rsct fields of the token
+func (adapter *AzureBlobAdapter) TemporaryServingObjectWithOptions(ctx context.Context, container, key string, expiredDateTime time.Time, azureClient interface{}, options ResponseOptions) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", azureError("sign", container, key, err)
+	}
+
+	signer, err := adapter.signer()
+	if err != nil {
+		return "", azureError("sign", container, key, err)
+	}
+
+	u, err := adapter.objectURL(container, key)
+	if err != nil {
+		return "", azureError("sign", container, key, err)
+	}
+
+	u.RawQuery = signer.serviceSAS(container, key, "r", expiredDateTime, options).Encode()
+	return u.String(), nil
+}
+
+// SignedUploadURL : Append a create and write service SAS token to the blob url.
+// A SAS can't bind the headers, so the content type is only sent along and the
+// md5 is checked by azure against the body
+func (adapter *AzureBlobAdapter) SignedUploadURL(ctx context.Context, container, key string, options SignOptions) (*SignedRequest, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, azureError("sign", container, key, err)
+	}
+
+	if options.MaxSize > 0 {
+		return nil, azureError("sign", container, key, errMaxSizeNotSupported("azure"))
+	}
+
+	signer, err := adapter.signer()
+	if err != nil {
+		return nil, azureError("sign", container, key, err)
+	}
+
+	u, err := adapter.objectURL(container, key)
+	if err != nil {
+		return nil, azureError("sign", container, key, err)
+	}
+	u.RawQuery = signer.serviceSAS(container, key, "cw", options.Expires, ResponseOptions{}).Encode()
+
+	header := options.uploadHeaders()
+	header.Set("X-Ms-Blob-Type", "BlockBlob")
+	return &SignedRequest{Method: http.MethodPut, URL: u.String(), Headers: header}, nil
+}
+
+// UploadReader :
+func (adapter *AzureBlobAdapter) UploadReader(container, filename string, reader io.Reader, contentType string) (string, error) {
+	return adapter.UploadReaderWithContext(context.Background(), container, filename, reader, contentType)
+}
+
+// UploadReaderWithContext :
+func (adapter *AzureBlobAdapter) UploadReaderWithContext(ctx context.Context, container, filename string, reader io.Reader, contentType string) (string, error) {
+	result, err := adapter.Put(ctx, container, filename, reader, contentType)
+	if err != nil {
+		return "", err
+	}
+
+	return result.URL, nil
+}
+
+// Put : Upload the reader as a block blob and return the canonical identifiers of
+// the object. It is put in a single request when it fits in one block, and
+// staged block by block otherwise
+func (adapter *AzureBlobAdapter) Put(ctx context.Context, container, filename string, reader io.Reader, contentType string) (*UploadResult, error) {
+	return adapter.PutWithAttributes(ctx, container, filename, reader, contentType, ObjectAttributes{})
+}
+
+// PutWithAttributes : Same as Put, the metadata are set as x-ms-meta-* and the tags
+// with x-ms-tags
+func (adapter *AzureBlobAdapter) PutWithAttributes(ctx context.Context, container, filename string, reader io.Reader, contentType string, attrs ObjectAttributes) (*UploadResult, error) {
+	return adapter.Upload(ctx, container, filename, reader, UploadOptions{ContentType: contentType, Attributes: attrs})
+}
+
+// Upload : The options are sent with the put or the block list, the storage class
+// is the access tier of the blob. Azure has no object acl, the access is set on the
+// container
+func (adapter *AzureBlobAdapter) Upload(ctx context.Context, container, filename string, reader io.Reader, options UploadOptions) (*UploadResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, azureError("upload", container, filename, err)
+	}
+
+	if options.ACL != "" {
+		return nil, azureError("upload", container, filename, errUploadOptionNotSupported("azure", "object acl"))
+	}
+
+	var head []byte
+	if options.ContentType == "" {
+		var err error
+		if head, reader, err = peekContent(reader); err != nil {
+			msg := fmt.Errorf("Could not read file: %w", err)
+			return nil, azureError("upload", container, filename, msg)
+		}
+	}
+
+	upload, err := adapter.newUpload(ctx, container, filename, options.ContentType, head)
+	if err != nil {
+		return nil, azureError("upload", container, filename, err)
+	}
+	for key, values := range azureAttributesHeader(options.Attributes) {
+		upload.header[key] = values
+	}
+	if options.ContentDisposition != "" {
+		upload.header.Set("X-Ms-Blob-Content-Disposition", options.ContentDisposition)
+	}
+	if options.StorageClass != "" {
+		upload.header.Set("X-Ms-Access-Tier", options.StorageClass)
+	}
+	// the staged blocks don't replace the blob, only the commit is conditional
+	if options.IfNotExists {
+		upload.header.Set("If-None-Match", "*")
+	}
+
+	hr := newHashReader(newContextReader(ctx, reader))
+	if err := upload.copy(hr); err != nil {
+		msg := fmt.Errorf("Could not write file: %w", err)
+		return nil, azureError("upload", container, filename, msg)
+	}
+
+	if err := hr.verify(options.MD5); err != nil {
+		return nil, azureError("upload", container, filename, err)
+	}
+
+	result, err := upload.commit()
+	if err != nil {
+		return nil, azureError("upload", container, filename, err)
+	}
+	result.MD5 = hr.md5.Sum(nil)
+	result.CRC32C = hr.crc32c.Sum32()
+
+	return result, nil
+}
+
+// ReadFile :
+func (adapter *AzureBlobAdapter) ReadFile(container, path string) ([]byte, error) {
+	return adapter.ReadFileWithContext(context.Background(), container, path)
+}
+
+// ReadFileWithContext : Convenience wrapper over OpenReader, the whole object is kept in memory
+func (adapter *AzureBlobAdapter) ReadFileWithContext(ctx context.Context, container, path string) ([]byte, error) {
+	rc, err := adapter.OpenRangeReader(ctx, container, path, 0, -1)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	data, err := ioutil.ReadAll(rc)
+	if err != nil {
+		return nil, azureError("read", container, path, err)
+	}
+
+	return data, nil
+}
+
+// OpenRangeReader : Read length bytes from offset, a negative length reads until the end of the object
+func (adapter *AzureBlobAdapter) OpenRangeReader(ctx context.Context, container, path string, offset, length int64) (io.ReadCloser, error) {
+	header := make(http.Header)
+	if length == 0 {
+		return ioutil.NopCloser(bytes.NewReader(nil)), nil
+	} else if length > 0 {
+		header.Set("X-Ms-Range", fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+	} else if offset > 0 {
+		header.Set("X-Ms-Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := adapter.do(ctx, http.MethodGet, container, path, nil, header, nil)
+	if err != nil {
+		// reading past the end of the object returns nothing, same as the other adapters
+		if e, ok := err.(*AzureServiceError); ok && e.Code == "InvalidRange" {
+			return ioutil.NopCloser(bytes.NewReader(nil)), nil
+		}
+		return nil, azureError("read", container, path, err)
+	}
+
+	return resp.Body, nil
+}
+
+// UploadBuffer :
+func (adapter *AzureBlobAdapter) UploadBuffer(container, filename string, contentType string) (*Buffer, error) {
+	return adapter.UploadBufferWithContext(context.Background(), container, filename, contentType)
+}
+
+// UploadBufferWithContext : The data is staged block by block, and the block list
+// is committed by Close
+func (adapter *AzureBlobAdapter) UploadBufferWithContext(ctx context.Context, container, filename string, contentType string) (*Buffer, error) {
+	buf := new(Buffer)
+	buf.adapter = AZURE
+	buf.ctx = ctx
+
+	if err := ctx.Err(); err != nil {
+		return nil, azureError("upload", container, filename, err)
+	}
+
+	upload, err := adapter.newUpload(ctx, container, filename, contentType, nil)
+	if err != nil {
+		return nil, azureError("upload", container, filename, err)
+	}
+
+	buf.azure = upload
+	buf.filename = filename
+	buf.bucket = container
+	return buf, nil
+}
+
+// List : List Blobs, the marker is used as the page token
+func (adapter *AzureBlobAdapter) List(ctx context.Context, container string, options ListOptions) (*ListResult, error) {
+	pageSize := options.PageSize
+	if pageSize <= 0 {
+		pageSize = defaultListPageSize
+	}
+
+	query := make(url.Values)
+	query.Set("restype", "container")
+	query.Set("comp", "list")
+	query.Set("maxresults", strconv.Itoa(pageSize))
+	if options.Prefix != "" {
+		query.Set("prefix", options.Prefix)
+	}
+	if options.Delimiter != "" {
+		query.Set("delimiter", options.Delimiter)
+	}
+	if options.PageToken != "" {
+		query.Set("marker", options.PageToken)
+	}
+
+	resp, err := adapter.do(ctx, http.MethodGet, container, "", query, nil, nil)
+	if err != nil {
+		return nil, azureError("list", container, options.Prefix, err)
+	}
+	defer resp.Body.Close()
+
+	var blobs struct {
+		NextMarker string
+		Blobs      []struct {
+			Name       string
+			Properties struct {
+				ContentLength int64  `xml:"Content-Length"`
+				ContentType   string `xml:"Content-Type"`
+				ETag          string `xml:"Etag"`
+				CreationTime  string `xml:"Creation-Time"`
+				LastModified  string `xml:"Last-Modified"`
+			}
+		} `xml:"Blobs>Blob"`
+		Prefixes []struct {
+			Name string
+		} `xml:"Blobs>BlobPrefix"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&blobs); err != nil {
+		return nil, azureError("list", container, options.Prefix, err)
+	}
+
+	result := new(ListResult)
+	result.NextPageToken = blobs.NextMarker
+	for _, o := range blobs.Blobs {
+		info := &ObjectInfo{
+			Name:        o.Name,
+			Size:        o.Properties.ContentLength,
+			ContentType: o.Properties.ContentType,
+			ETag:        strings.Trim(o.Properties.ETag, `"`),
+		}
+		info.Created, _ = http.ParseTime(o.Properties.CreationTime)
+		info.Updated, _ = http.ParseTime(o.Properties.LastModified)
+		result.Objects = append(result.Objects, info)
+	}
+	for _, prefix := range blobs.Prefixes {
+		result.Objects = append(result.Objects, &ObjectInfo{Prefix: prefix.Name})
+	}
+
+	return result, nil
+}
+
+// Stat : Return the blob properties through a HEAD request. Azure has no crc32c,
+// and only returns the md5 when the blob was put in a single request
+func (adapter *AzureBlobAdapter) Stat(ctx context.Context, container, filename string) (*ObjectInfo, error) {
+	resp, err := adapter.do(ctx, http.MethodHead, container, filename, nil, nil, nil)
+	if err != nil {
+		return nil, azureError("stat", container, filename, err)
+	}
+	resp.Body.Close()
+
+	header := resp.Header
+	info := new(ObjectInfo)
+	info.Name = filename
+	info.Size, _ = strconv.ParseInt(header.Get("Content-Length"), 10, 64)
+	info.ContentType = header.Get("Content-Type")
+	info.ContentDisposition = header.Get("Content-Disposition")
+	info.ContentEncoding = header.Get("Content-Encoding")
+	info.ContentLanguage = header.Get("Content-Language")
+	info.CacheControl = header.Get("Cache-Control")
+	info.ETag = strings.Trim(header.Get("ETag"), `"`)
+	info.MD5, _ = base64.StdEncoding.DecodeString(header.Get("Content-MD5"))
+	info.Created, _ = http.ParseTime(header.Get("X-Ms-Creation-Time"))
+	info.Updated, _ = http.ParseTime(header.Get("Last-Modified"))
+	info.Metadata = make(map[string]string)
+	for key := range header {
+		if strings.HasPrefix(key, azureMetaPrefix) {
+			info.Metadata[strings.ToLower(key[len(azureMetaPrefix):])] = header.Get(key)
+		}
+	}
+
+	return info, nil
+}
+
+// Close : The adapter holds no connection of its own, the idle ones of the http
+// client are closed
+func (adapter *AzureBlobAdapter) Close() error {
+	if adapter.HTTPClient != nil {
+		adapter.HTTPClient.CloseIdleConnections()
+	}
+	return nil
+}
+
+func (adapter *AzureBlobAdapter) uploadResult(container, filename string, size int64, header http.Header) *UploadResult {
+	contentType, _, _ := resolveContentType(filename, "", nil)
+	if ct := header.Get("X-Ms-Blob-Content-Type"); ct != "" {
+		contentType = ct
+	}
+
+	fileURL := ""
+	if u, err := adapter.objectURL(container, filename); err == nil {
+		fileURL = u.String()
+	}
+
+	return &UploadResult{
+		Bucket:      container,
+		Key:         filename,
+		URL:         fileURL,
+		Size:        size,
+		ContentType: contentType,
+	}
+}
+
+func (adapter *AzureBlobAdapter) signer() (*azureSigner, error) {
+	if adapter.AccountName == "" {
+		return nil, fmt.Errorf("%w: azure account name is required", ErrInvalidClient)
+	}
+	return newAzureSigner(adapter.AccountName, adapter.AccountKey)
+}
+
+func (adapter *AzureBlobAdapter) httpClient() *http.Client {
+	if adapter.HTTPClient != nil {
+		return adapter.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+func (adapter *AzureBlobAdapter) endpoint() (*url.URL, error) {
+	endpoint := adapter.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://%s%s", adapter.AccountName, azureStorageHost)
+	} else if !strings.Contains(endpoint, "://") {
+		endpoint = "https://" + endpoint
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid azure endpoint %q: %v", ErrInvalidClient, adapter.Endpoint, err)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("%w: invalid azure endpoint %q", ErrInvalidClient, adapter.Endpoint)
+	}
+
+	return u, nil
+}
+
+// objectURL : The url of the blob, or of the container when the key is empty
+func (adapter *AzureBlobAdapter) objectURL(container, key string) (*url.URL, error) {
+	if container == "" {
+		return nil, fmt.Errorf("storage: bucket is required")
+	}
+
+	u, err := adapter.endpoint()
+	if err != nil {
+		return nil, err
+	}
+
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/" + container
+	if key != "" {
+		u.Path += "/" + key
+	}
+	u.RawPath = ""
+	u.RawQuery = ""
+	return u, nil
+}
+
+// objectKey : Strip the endpoint of the adapter from the url, so the urls of the
+// emulator are understood too
+func (adapter *AzureBlobAdapter) objectKey(container, fileURL string) (string, error) {
+	if !strings.Contains(fileURL, "://") {
+		return fileURL, nil
+	}
+
+	base, err := adapter.objectURL(container, "")
+	if err != nil {
+		return "", err
+	}
+
+	u, err := url.Parse(fileURL)
+	if err == nil && strings.EqualFold(u.Host, base.Host) {
+		prefix := base.Path + "/"
+		if strings.HasPrefix(u.Path, prefix) && len(u.Path) > len(prefix) {
+			return strings.TrimPrefix(u.Path, prefix), nil
+		}
+	}
+
+	return objectKeyFromURL(container, fileURL)
+}
+
+// do : Sign and send the request, the response is returned only when it succeeded
+// and its body must be closed by the caller
+func (adapter *AzureBlobAdapter) do(ctx context.Context, method, container, key string, query url.Values, header http.Header, body []byte) (*http.Response, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	signer, err := adapter.signer()
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := adapter.objectURL(container, key)
+	if err != nil {
+		return nil, err
+	}
+	if query != nil {
+		u.RawQuery = query.Encode()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = int64(len(body))
+	if len(body) == 0 {
+		req.Body = http.NoBody
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	signer.sign(req, time.Now())
+
+	resp, err := adapter.httpClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		defer resp.Body.Close()
+		return nil, readAzureServiceError(resp)
+	}
+
+	return resp, nil
+}
+
+func readAzureServiceError(resp *http.Response) error {
+	e := &AzureServiceError{StatusCode: resp.StatusCode}
+	if data, err := ioutil.ReadAll(resp.Body); err == nil && len(data) > 0 {
+		xml.Unmarshal(data, e)
+	}
+	if e.Code == "" {
+		e.Code = resp.Header.Get("X-Ms-Error-Code")
+	}
+	e.RequestID = resp.Header.Get("X-Ms-Request-Id")
+
+	return e
+}
+
+// azureAttributesHeader : The attributes as the headers of Put Blob, Put Block List
+// and Set Blob Properties
+func azureAttributesHeader(attrs ObjectAttributes) http.Header {
+	header := make(http.Header)
+	if attrs.CacheControl != "" {
+		header.Set("X-Ms-Blob-Cache-Control", attrs.CacheControl)
+	}
+	if attrs.ContentEncoding != "" {
+		header.Set("X-Ms-Blob-Content-Encoding", attrs.ContentEncoding)
+	}
+	if attrs.ContentLanguage != "" {
+		header.Set("X-Ms-Blob-Content-Language", attrs.ContentLanguage)
+	}
+	for key, value := range attrs.Metadata {
+		header.Set(azureMetaPrefix+key, value)
+	}
+	if attrs.Tags != nil {
+		header.Set("X-Ms-Tags", tagQuery(attrs.Tags))
+	}
+
+	return header
+}
+
+// azureContainerPattern : Lowercase letters, digits and single dashes between them
+var azureContainerPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// validateBucket : The container names are 3 to 63 characters, without dots,
+// underscores or two dashes in a row
+func (adapter *AzureBlobAdapter) validateBucket(container string) error {
+	if len(container) < 3 || len(container) > 63 || !azureContainerPattern.MatchString(container) {
+		return errInvalidBucketName(container)
+	}
+
+	return nil
+}
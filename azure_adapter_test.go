@@ -0,0 +1,244 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var azureTestKey = base64.StdEncoding.EncodeToString([]byte("azure-test-account-key"))
+
+// fakeAzure : Keep the blobs of the account and check the shared key or the
+// service sas of every request, built from the documentation of the service
+type fakeAzure struct {
+	t     *testing.T
+	mu    sync.Mutex
+	blobs map[string][]byte
+	meta  map[string]http.Header
+}
+
+func newFakeAzure(t *testing.T) (*fakeAzure, *AzureBlobAdapter, func()) {
+	fake := &fakeAzure{t: t, blobs: make(map[string][]byte), meta: make(map[string]http.Header)}
+	server := httptest.NewServer(fake)
+	adapter := &AzureBlobAdapter{AccountName: "account", AccountKey: azureTestKey, Endpoint: server.URL}
+	return fake, adapter, server.Close
+}
+
+func azureTestSignature(stringToSign string) string {
+	key, _ := base64.StdEncoding.DecodeString(azureTestKey)
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(stringToSign))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// sharedKey : The signature of the request, see
+// https://docs.microsoft.com/en-us/rest/api/storageservices/authorize-with-shared-key
+func (fake *fakeAzure) sharedKey(r *http.Request) string {
+	contentLength := ""
+	if r.ContentLength > 0 {
+		contentLength = strconv.FormatInt(r.ContentLength, 10)
+	}
+
+	var names []string
+	for key := range r.Header {
+		if strings.HasPrefix(strings.ToLower(key), "x-ms-") {
+			names = append(names, strings.ToLower(key))
+		}
+	}
+	sort.Strings(names)
+	var headers strings.Builder
+	for _, name := range names {
+		headers.WriteString(name + ":" + r.Header.Get(name) + "\n")
+	}
+
+	resource := "/account" + r.URL.EscapedPath()
+	query := r.URL.Query()
+	var params []string
+	for name := range query {
+		params = append(params, name)
+	}
+	sort.Strings(params)
+	for _, name := range params {
+		resource += "\n" + strings.ToLower(name) + ":" + strings.Join(query[name], ",")
+	}
+
+	return azureTestSignature(strings.Join([]string{
+		r.Method,
+		r.Header.Get("Content-Encoding"),
+		r.Header.Get("Content-Language"),
+		contentLength,
+		r.Header.Get("Content-MD5"),
+		r.Header.Get("Content-Type"),
+		"",
+		r.Header.Get("If-Modified-Since"),
+		r.Header.Get("If-Match"),
+		r.Header.Get("If-None-Match"),
+		r.Header.Get("If-Unmodified-Since"),
+		r.Header.Get("Range"),
+		headers.String() + resource,
+	}, "\n"))
+}
+
+// serviceSAS : The signature of the token in the query, see
+// https://docs.microsoft.com/en-us/rest/api/storageservices/create-service-sas
+func (fake *fakeAzure) serviceSAS(r *http.Request) string {
+	query := r.URL.Query()
+	return azureTestSignature(strings.Join([]string{
+		query.Get("sp"),
+		query.Get("st"),
+		query.Get("se"),
+		"/blob/account" + r.URL.Path,
+		query.Get("si"),
+		query.Get("sip"),
+		query.Get("spr"),
+		query.Get("sv"),
+		query.Get("sr"),
+		"",
+		query.Get("rscc"),
+		query.Get("rscd"),
+		query.Get("rsce"),
+		query.Get("rscl"),
+		query.Get("rsct"),
+	}, "\n"))
+}
+
+func (fake *fakeAzure) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+
+	query := r.URL.Query()
+	if sig := query.Get("sig"); sig != "" {
+		expiry, err := time.Parse(azureTimeFormat, query.Get("se"))
+		if sig != fake.serviceSAS(r) || err != nil || time.Now().After(expiry) {
+			w.Header().Set("X-Ms-Error-Code", "AuthenticationFailed")
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if query.Get("rsct") != "" {
+			w.Header().Set("Content-Type", query.Get("rsct"))
+		}
+	} else if auth := r.Header.Get("Authorization"); auth != "SharedKey account:"+fake.sharedKey(r) {
+		fake.t.Errorf("%s %s: unexpected Authorization %q", r.Method, r.URL, auth)
+		w.Header().Set("X-Ms-Error-Code", "AuthenticationFailed")
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPut:
+		if r.Header.Get("X-Ms-Blob-Type") != "BlockBlob" {
+			fake.t.Errorf("expected a block blob, got %q", r.Header.Get("X-Ms-Blob-Type"))
+		}
+		fake.blobs[r.URL.Path] = body
+		fake.meta[r.URL.Path] = r.Header
+		w.Header().Set("ETag", `"0x1"`)
+		w.WriteHeader(http.StatusCreated)
+	case http.MethodGet:
+		data, isExist := fake.blobs[r.URL.Path]
+		if !isExist {
+			w.Header().Set("X-Ms-Error-Code", "BlobNotFound")
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(data)
+	case http.MethodDelete:
+		if _, isExist := fake.blobs[r.URL.Path]; !isExist {
+			w.Header().Set("X-Ms-Error-Code", "BlobNotFound")
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		delete(fake.blobs, r.URL.Path)
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+func TestAzureSharedKey(t *testing.T) {
+	ctx := context.Background()
+	fake, adapter, stop := newFakeAzure(t)
+	defer stop()
+
+	result, err := adapter.Put(ctx, "container", "dir/my report.csv", strings.NewReader("a,b\n1,2\n"), ContentTypeCSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Size != 8 || result.ContentType != "text/csv" {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	header := fake.meta["/container/dir/my report.csv"]
+	if header.Get("X-Ms-Blob-Content-Type") != "text/csv" {
+		t.Errorf("the content type is not sent: %v", header)
+	}
+
+	data, err := adapter.ReadFileWithContext(ctx, "container", "dir/my report.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "a,b\n1,2\n" {
+		t.Errorf("read %q", data)
+	}
+
+	if _, err := adapter.ReadFileWithContext(ctx, "container", "missing.csv"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestAzureServiceSAS(t *testing.T) {
+	ctx := context.Background()
+	fake, adapter, stop := newFakeAzure(t)
+	defer stop()
+	fake.blobs["/container/report.pdf"] = []byte("%PDF-1.4")
+
+	signedURL, err := adapter.TemporaryServingObject(ctx, "container", "report.pdf", time.Now().Add(time.Hour), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := http.Get(signedURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(data) != "%PDF-1.4" {
+		t.Fatalf("expected the signed url to be accepted, got %s", resp.Status)
+	}
+
+	// a tampered permission must break the signature
+	resp, err = http.Get(strings.Replace(signedURL, "sp=r", "sp=rw", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected a tampered url to be refused, got %s", resp.Status)
+	}
+
+	expired, err := adapter.TemporaryServingObject(ctx, "container", "report.pdf", time.Now().Add(-time.Minute), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err = http.Get(expired)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected an expired url to be refused, got %s", resp.Status)
+	}
+}
@@ -0,0 +1,141 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// azureBlockSize : Size of the staged blocks, the blob is put in a single request
+// when it fits in one
+const azureBlockSize = 4 << 20
+
+// azureUpload : Buffer the data until a block is full. The staged blocks are only
+// visible once the block list is committed, the uncommitted ones are garbage
+// collected by azure after a week so a failed upload needs no cleanup
+type azureUpload struct {
+	ctx       context.Context
+	adapter   *AzureBlobAdapter
+	container string
+	key       string
+	header    http.Header
+	blockIDs  []string
+	pending   bytes.Buffer
+	size      int64
+}
+
+func (adapter *AzureBlobAdapter) newUpload(ctx context.Context, container, key, contentType string, head []byte) (*azureUpload, error) {
+	ct, disposition, err := resolveContentType(key, contentType, head)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make(http.Header)
+	if ct != "" {
+		header.Set("X-Ms-Blob-Content-Type", ct)
+	}
+	if disposition != "" {
+		header.Set("X-Ms-Blob-Content-Disposition", disposition)
+	}
+
+	return &azureUpload{
+		ctx:       ctx,
+		adapter:   adapter,
+		container: container,
+		key:       key,
+		header:    header,
+	}, nil
+}
+
+// copy : Stage a block every time the pending data fills one
+func (upload *azureUpload) copy(reader io.Reader) error {
+	for {
+		n, err := io.CopyN(&upload.pending, reader, int64(azureBlockSize-upload.pending.Len()))
+		upload.size += n
+		if upload.pending.Len() >= azureBlockSize {
+			if err := upload.stageBlock(); err != nil {
+				return err
+			}
+		}
+
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+func (upload *azureUpload) stageBlock() error {
+	// the ids of a blob must all have the same length
+	blockID := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%08d", len(upload.blockIDs))))
+
+	query := make(url.Values)
+	query.Set("comp", "block")
+	query.Set("blockid", blockID)
+
+	resp, err := upload.adapter.do(upload.ctx, http.MethodPut, upload.container, upload.key, query, nil, upload.pending.Bytes())
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	upload.blockIDs = append(upload.blockIDs, blockID)
+	upload.pending.Reset()
+	return nil
+}
+
+// commit : Put the pending data, or commit the staged blocks
+func (upload *azureUpload) commit() (*UploadResult, error) {
+	if len(upload.blockIDs) == 0 {
+		header := make(http.Header)
+		for k, v := range upload.header {
+			header[k] = v
+		}
+		header.Set("X-Ms-Blob-Type", "BlockBlob")
+
+		resp, err := upload.adapter.do(upload.ctx, http.MethodPut, upload.container, upload.key, nil, header, upload.pending.Bytes())
+		if err != nil {
+			return nil, err
+		}
+		resp.Body.Close()
+
+		return upload.result(resp.Header), nil
+	}
+
+	if upload.pending.Len() > 0 {
+		if err := upload.stageBlock(); err != nil {
+			return nil, err
+		}
+	}
+
+	body, err := xml.Marshal(struct {
+		XMLName xml.Name `xml:"BlockList"`
+		Latest  []string `xml:"Latest"`
+	}{Latest: upload.blockIDs})
+	if err != nil {
+		return nil, err
+	}
+
+	query := url.Values{"comp": {"blocklist"}}
+	resp, err := upload.adapter.do(upload.ctx, http.MethodPut, upload.container, upload.key, query, upload.header, append([]byte(xml.Header), body...))
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	return upload.result(resp.Header), nil
+}
+
+func (upload *azureUpload) result(header http.Header) *UploadResult {
+	result := upload.adapter.uploadResult(upload.container, upload.key, upload.size, upload.header)
+	result.ETag = strings.Trim(header.Get("ETag"), `"`)
+	result.VersionID = header.Get("X-Ms-Version-Id")
+	return result
+}
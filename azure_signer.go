@@ -0,0 +1,155 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	azureVersion    = "2020-04-08"
+	azureTimeFormat = "2006-01-02T15:04:05Z"
+)
+
+// azureSigner : Shared key authorization, see
+// https://docs.microsoft.com/en-us/rest/api/storageservices/authorize-with-shared-key
+type azureSigner struct {
+	accountName string
+	accountKey  []byte
+}
+
+func newAzureSigner(accountName, accountKey string) (*azureSigner, error) {
+	key, err := base64.StdEncoding.DecodeString(accountKey)
+	if err == nil && len(key) == 0 {
+		err = fmt.Errorf("the key is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid azure account key: %v", ErrInvalidClient, err)
+	}
+
+	return &azureSigner{accountName: accountName, accountKey: key}, nil
+}
+
+// sign : Sign the request in the Authorization header
+func (signer *azureSigner) sign(req *http.Request, now time.Time) {
+	req.Header.Set("X-Ms-Date", now.UTC().Format(http.TimeFormat))
+	req.Header.Set("X-Ms-Version", azureVersion)
+
+	contentLength := ""
+	if req.ContentLength > 0 {
+		contentLength = strconv.FormatInt(req.ContentLength, 10)
+	}
+
+	stringToSign := strings.Join([]string{
+		req.Method,
+		req.Header.Get("Content-Encoding"),
+		req.Header.Get("Content-Language"),
+		contentLength,
+		req.Header.Get("Content-MD5"),
+		req.Header.Get("Content-Type"),
+		"", // the date is given by x-ms-date
+		req.Header.Get("If-Modified-Since"),
+		req.Header.Get("If-Match"),
+		req.Header.Get("If-None-Match"),
+		req.Header.Get("If-Unmodified-Since"),
+		req.Header.Get("Range"),
+		signer.canonicalHeaders(req.Header) + signer.canonicalResource(req.URL),
+	}, "\n")
+
+	req.Header.Set("Authorization", fmt.Sprintf("SharedKey %s:%s", signer.accountName, signer.signature(stringToSign)))
+}
+
+// serviceSAS : The query of a blob service sas, see
+// https://docs.microsoft.com/en-us/rest/api/storageservices/create-service-sas.
+// The response overrides are signed, and sent in the rsc* fields
+func (signer *azureSigner) serviceSAS(container, blob, permissions string, expiry time.Time, response ResponseOptions) url.Values {
+	expiredAt := expiry.UTC().Format(azureTimeFormat)
+	resource := fmt.Sprintf("/blob/%s/%s/%s", signer.accountName, container, blob)
+
+	stringToSign := strings.Join([]string{
+		permissions,
+		"", // start
+		expiredAt,
+		resource,
+		"", // identifier
+		"", // ip
+		"", // protocol
+		azureVersion,
+		"b",
+		"", // snapshot time
+		response.CacheControl,
+		response.ContentDisposition,
+		"", // content encoding
+		"", // content language
+		response.ContentType,
+	}, "\n")
+
+	query := make(url.Values)
+	query.Set("sv", azureVersion)
+	query.Set("sr", "b")
+	query.Set("sp", permissions)
+	query.Set("se", expiredAt)
+	if response.CacheControl != "" {
+		query.Set("rscc", response.CacheControl)
+	}
+	if response.ContentDisposition != "" {
+		query.Set("rscd", response.ContentDisposition)
+	}
+	if response.ContentType != "" {
+		query.Set("rsct", response.ContentType)
+	}
+	query.Set("sig", signer.signature(stringToSign))
+	return query
+}
+
+func (signer *azureSigner) signature(stringToSign string) string {
+	mac := hmac.New(sha256.New, signer.accountKey)
+	mac.Write([]byte(stringToSign))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (signer *azureSigner) canonicalHeaders(header http.Header) string {
+	names := make([]string, 0)
+	values := make(map[string]string)
+	for key := range header {
+		name := strings.ToLower(key)
+		if strings.HasPrefix(name, "x-ms-") {
+			names = append(names, name)
+			values[name] = strings.TrimSpace(strings.Join(header[key], ","))
+		}
+	}
+	sort.Strings(names)
+
+	var canonical strings.Builder
+	for _, name := range names {
+		canonical.WriteString(name + ":" + values[name] + "\n")
+	}
+	return canonical.String()
+}
+
+// canonicalResource : The account followed by the path as it is sent, the path
+// of the emulator already starts with the account
+func (signer *azureSigner) canonicalResource(u *url.URL) string {
+	resource := "/" + signer.accountName + u.EscapedPath()
+
+	query := u.Query()
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		values := append([]string(nil), query[name]...)
+		sort.Strings(values)
+		resource += "\n" + strings.ToLower(name) + ":" + strings.Join(values, ",")
+	}
+	return resource
+}
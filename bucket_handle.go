@@ -0,0 +1,205 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// BucketHandle : Run the operations of the builder on a single bucket. The name is
+// validated when the handle is created, and every operation returns the error
+type BucketHandle struct {
+	builder *Builder
+	name    string
+	err     error
+}
+
+// bucketValidator : Implemented by the adapters to check the bucket names against
+// the rules of their provider, the others are checked against bucketNamePattern
+type bucketValidator interface {
+	validateBucket(name string) error
+}
+
+// bucketNamePattern : The loose rules of the adapters registered without a validator,
+// lowercase letters, digits, dots, dashes and underscores, starting and ending with
+// a letter or digit
+var bucketNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]{1,61}[a-z0-9]$`)
+
+// Bucket : Scope the operations to the bucket, so the bucket is not passed on
+// every call. The bucket methods of the builder keep working
+func (b *Builder) Bucket(name string) *BucketHandle {
+	handle := &BucketHandle{builder: b, name: name}
+	if b.err != nil {
+		handle.err = b.err
+		return handle
+	}
+
+	handle.err = validateBucketName(b.adapter, name)
+	return handle
+}
+
+func validateBucketName(adapter Adapter, name string) error {
+	if validator, ok := adapter.(bucketValidator); ok {
+		return validator.validateBucket(name)
+	}
+
+	if !bucketNamePattern.MatchString(name) || strings.Contains(name, "..") {
+		return errInvalidBucketName(name)
+	}
+
+	return nil
+}
+
+func errInvalidBucketName(name string) error {
+	return fmt.Errorf("storage: invalid bucket name %q", name)
+}
+
+// Name : The bucket of the handle
+func (b *BucketHandle) Name() string {
+	return b.name
+}
+
+// Builder : The builder the handle runs on, for the operations it doesn't expose
+func (b *BucketHandle) Builder() *Builder {
+	return b.builder
+}
+
+// Upload : Upload the reader with the options and return the canonical identifiers
+// of the object, same as Builder.Upload
+func (b *BucketHandle) Upload(ctx context.Context, name string, reader io.Reader, options ...UploadOption) (*UploadResult, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	return b.builder.Upload(ctx, b.name, name, reader, options...)
+}
+
+// Read : Read the whole object in memory
+func (b *BucketHandle) Read(ctx context.Context, name string) ([]byte, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	return b.builder.ReadFileWithContext(ctx, b.name, name)
+}
+
+// OpenReader : Stream the object, the caller must close the reader
+func (b *BucketHandle) OpenReader(ctx context.Context, name string) (io.ReadCloser, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	return b.builder.OpenReaderWithContext(ctx, b.name, name)
+}
+
+// Delete : Delete the object by its key, a missing object is not an error
+func (b *BucketHandle) Delete(ctx context.Context, name string) error {
+	if b.err != nil {
+		return b.err
+	}
+
+	return b.builder.DeleteObjectWithContext(ctx, b.name, name)
+}
+
+// DeleteMany : Delete the objects by their keys, the failed keys are returned in a *DeleteError
+func (b *BucketHandle) DeleteMany(ctx context.Context, names []string) error {
+	if b.err != nil {
+		return b.err
+	}
+
+	return b.builder.DeleteManyWithContext(ctx, b.name, names)
+}
+
+// DeletePrefix : Delete every object whose key begins with the prefix
+func (b *BucketHandle) DeletePrefix(ctx context.Context, prefix string) error {
+	if b.err != nil {
+		return b.err
+	}
+
+	return b.builder.DeletePrefixWithContext(ctx, b.name, prefix)
+}
+
+// Stat : Return the object attributes, or ErrNotFound when it doesn't exist
+func (b *BucketHandle) Stat(ctx context.Context, name string) (*ObjectInfo, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	return b.builder.StatWithContext(ctx, b.name, name)
+}
+
+// UpdateMetadata : Change the attributes of the object without uploading it again
+func (b *BucketHandle) UpdateMetadata(ctx context.Context, name string, attrs ObjectAttributes) error {
+	if b.err != nil {
+		return b.err
+	}
+
+	return b.builder.UpdateMetadataWithContext(ctx, b.name, name, attrs)
+}
+
+// Exists : Check the object exists without downloading it
+func (b *BucketHandle) Exists(ctx context.Context, name string) (bool, error) {
+	if b.err != nil {
+		return false, b.err
+	}
+
+	return b.builder.ExistsWithContext(ctx, b.name, name)
+}
+
+// List : Iterate over the objects of the bucket
+func (b *BucketHandle) List(ctx context.Context, options ListOptions) *ObjectIterator {
+	if b.err != nil {
+		return &ObjectIterator{ctx: ctx, bucket: b.name, options: options, err: b.err}
+	}
+	return b.builder.List(ctx, b.name, options)
+}
+
+// SignURL : Return a url serving the object until expiredTime, signed with the
+// credentials of the adapter
+func (b *BucketHandle) SignURL(ctx context.Context, name string, expiredTime time.Time) (string, error) {
+	if b.err != nil {
+		return "", b.err
+	}
+
+	return b.builder.TemporaryServingObjectWithContext(ctx, b.name, name, expiredTime, nil)
+}
+
+// SignURLWithOptions : Same as SignURL, served with the response headers of the options
+func (b *BucketHandle) SignURLWithOptions(ctx context.Context, name string, expiredTime time.Time, options ResponseOptions) (string, error) {
+	if b.err != nil {
+		return "", b.err
+	}
+
+	return b.builder.TemporaryServingObjectWithOptions(ctx, b.name, name, expiredTime, nil, options)
+}
+
+// SignUploadURL : Return a PUT request the client sends to upload the object
+func (b *BucketHandle) SignUploadURL(ctx context.Context, name string, options SignOptions) (*SignedRequest, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	return b.builder.SignedUploadURLWithContext(ctx, b.name, name, options)
+}
+
+// PostPolicy : Return the url and fields of a browser form uploading to the bucket
+func (b *BucketHandle) PostPolicy(ctx context.Context, name string, options PolicyOptions) (*PostForm, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	return b.builder.PostPolicyWithContext(ctx, b.name, name, options)
+}
+
+// Close : Release the client of the builder, shared by every handle of the builder
+func (b *BucketHandle) Close() error {
+	return b.builder.Close()
+}
+
+// Err : The error of the bucket name, or of the builder the handle was created from
+func (b *BucketHandle) Err() error {
+	return b.err
+}
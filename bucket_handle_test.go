@@ -0,0 +1,152 @@
+package storage
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBucketNamePattern(t *testing.T) {
+	tests := []struct {
+		name  string
+		valid bool
+	}{
+		{"bucket", true},
+		{"my-bucket.example_1", true},
+		{"ab", false},
+		{"Bucket", false},
+		{"-bucket", false},
+		{"bucket-", false},
+		{"my..bucket", false},
+		{"my/bucket", false},
+		{"", false},
+	}
+
+	// the adapters registered without a validator get the loose pattern
+	for _, tt := range tests {
+		err := validateBucketName(putOnlyAdapter{NewMemoryAdapter()}, tt.name)
+		if (err == nil) != tt.valid {
+			t.Errorf("%q: valid = %v, got %v", tt.name, tt.valid, err)
+		}
+	}
+
+	// any directory name is a local bucket
+	local := &LocalAdapter{Root: "/data"}
+	for name, valid := range map[string]bool{"My_Bucket": true, "..": false, "a/b": false} {
+		if err := validateBucketName(local, name); (err == nil) != valid {
+			t.Errorf("local %q: valid = %v, got %v", name, valid, err)
+		}
+	}
+}
+
+func TestBucketHandleReturnsTheNameError(t *testing.T) {
+	ctx := context.Background()
+	adapter := NewMemoryAdapter()
+	bucket := New(adapter).Bucket("in/valid")
+	if bucket.Err() == nil {
+		t.Fatal("expected the bucket name to be refused")
+	}
+
+	if _, err := bucket.Upload(ctx, "report.csv", strings.NewReader("a,b")); err != bucket.Err() {
+		t.Errorf("Upload: expected the name error, got %v", err)
+	}
+	if _, err := bucket.Read(ctx, "report.csv"); err != bucket.Err() {
+		t.Errorf("Read: expected the name error, got %v", err)
+	}
+	if err := bucket.Delete(ctx, "report.csv"); err != bucket.Err() {
+		t.Errorf("Delete: expected the name error, got %v", err)
+	}
+	if _, err := bucket.Exists(ctx, "report.csv"); err != bucket.Err() {
+		t.Errorf("Exists: expected the name error, got %v", err)
+	}
+	if _, err := bucket.List(ctx, ListOptions{}).Next(); err != bucket.Err() {
+		t.Errorf("List: expected the name error, got %v", err)
+	}
+	if _, err := bucket.SignURL(ctx, "report.csv", time.Now().Add(time.Hour)); err != bucket.Err() {
+		t.Errorf("SignURL: expected the name error, got %v", err)
+	}
+	if objects := adapter.Objects("in/valid"); len(objects) != 0 {
+		t.Errorf("an object is stored in an invalid bucket: %v", objects)
+	}
+}
+
+func TestBucketHandleSharesTheBuilder(t *testing.T) {
+	ctx := context.Background()
+	builder := New(NewMemoryAdapter())
+	bucket := builder.Bucket("bucket")
+	if err := bucket.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := bucket.Upload(ctx, "report.csv", strings.NewReader("a,b")); err != nil {
+		t.Fatal(err)
+	}
+	data, err := builder.ReadFile("bucket", "report.csv")
+	if err != nil || string(data) != "a,b" {
+		t.Errorf("read %q, %v through the builder", data, err)
+	}
+
+	if err := New(struct{}{}).Bucket("bucket").Err(); err == nil {
+		t.Error("expected the builder error to be kept by the handle")
+	}
+}
+
+func TestValidateBucketName(t *testing.T) {
+	long := "a23456789.b23456789.c23456789.d23456789.e23456789.f23456789.g23456789"
+	tests := []struct {
+		adapter Adapter
+		name    string
+		valid   bool
+	}{
+		{&GCSAdapter{}, "my_bucket.example.com", true},
+		{&GCSAdapter{}, long, true},
+		{&GCSAdapter{}, "google-bucket", false},
+		{&GCSAdapter{}, "goog-bucket", false},
+		{&GCSAdapter{}, "a.." + long, false},
+		{&AliyunAdapter{}, "my-bucket", true},
+		{&AliyunAdapter{}, "my.bucket", false},
+		{&AliyunAdapter{}, "my_bucket", false},
+		{&S3Adapter{}, "my.bucket", true},
+		{&S3Adapter{}, "my_bucket", false},
+		{&S3Adapter{}, "my..bucket", false},
+		{&S3Adapter{}, "192.168.1.1", false},
+		{&S3Adapter{}, long, false},
+		{&AzureBlobAdapter{}, "my-container", true},
+		{&AzureBlobAdapter{}, "my--container", false},
+		{&AzureBlobAdapter{}, "my.container", false},
+		{&AzureBlobAdapter{}, "my_container", false},
+		{NewMemoryAdapter(), "a", true},
+		{NewMemoryAdapter(), "a/b", false},
+	}
+
+	for _, tt := range tests {
+		err := validateBucketName(tt.adapter, tt.name)
+		if (err == nil) != tt.valid {
+			t.Errorf("%T %q: valid = %v, got %v", tt.adapter, tt.name, tt.valid, err)
+		}
+	}
+}
+
+func TestBucketHandleUploadForwardsTheOptions(t *testing.T) {
+	ctx := context.Background()
+	bucket := New(MemoryClient{}).Bucket("bucket")
+
+	_, err := bucket.Upload(ctx, "report.csv", strings.NewReader("a,b"),
+		WithContentType(ContentTypeCSV), WithMetadata(map[string]string{"tenant": "a"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := bucket.Stat(ctx, "report.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.ContentType != "text/csv" || info.Metadata["tenant"] != "a" {
+		t.Errorf("the options are not applied: %+v", info)
+	}
+
+	if _, err := bucket.Upload(ctx, "report.bin", strings.NewReader("data")); err != nil {
+		t.Errorf("expected an upload without options to succeed, got %v", err)
+	}
+}
@@ -1,9 +1,13 @@
 package storage
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"io"
 	"mime/multipart"
+	"net/http"
+	"reflect"
 	"strings"
 	"time"
 )
@@ -14,92 +18,326 @@ type Builder struct {
 	err     error
 }
 
-// AliyunClient :
+// AliyunClient : SecurityToken goes with the STS access keys, set CredentialsProvider
+// instead for the credentials which expire, e.g. of a RAM role
 type AliyunClient struct {
+	Endpoint            string
+	AccessKeyID         string
+	AccessKeySecret     string
+	SecurityToken       string
+	CredentialsProvider AliyunCredentialsProvider
+}
+
+// Driver :
+func (AliyunClient) Driver() string {
+	return ALIYUN
+}
+
+// GoogleCloudStorageClient : The credential is one of Credential, CredentialsJSON or
+// CredentialsFile, the default credentials are used when they are all empty.
+// Endpoint overrides storage.googleapis.com, e.g. for fake-gcs-server
+type GoogleCloudStorageClient struct {
+	Credential      *GoogleClient
+	CredentialsJSON []byte
+	CredentialsFile string
+	Endpoint        string
+}
+
+// Driver :
+func (GoogleCloudStorageClient) Driver() string {
+	return GCS
+}
+
+// MemoryClient : Every builder created from it gets its own empty memory adapter,
+// pass a *MemoryAdapter to New instead to inspect the objects afterwards
+type MemoryClient struct {
+}
+
+// Driver :
+func (MemoryClient) Driver() string {
+	return MEMORY
+}
+
+// LocalClient : Root is the directory holding one sub directory per bucket
+type LocalClient struct {
+	Root string
+}
+
+// Driver :
+func (LocalClient) Driver() string {
+	return LOCAL
+}
+
+// S3Client : Endpoint is the AWS endpoint of the region when empty, set PathStyle
+// for MinIO and the other servers without virtual hosted buckets
+type S3Client struct {
 	Endpoint        string
+	Region          string
 	AccessKeyID     string
-	AccessKeySecret string
+	SecretAccessKey string
+	SessionToken    string
+	PathStyle       bool
+	HTTPClient      *http.Client
 }
 
-// GoogleCloudStorageClient :
-type GoogleCloudStorageClient struct {
+// Driver :
+func (S3Client) Driver() string {
+	return S3
 }
 
-var client = map[string]bool{
-	GCS: true,
+// AzureClient : Endpoint is the blob endpoint of the account when empty, e.g.
+// http://127.0.0.1:10000/devstoreaccount1 for the Azurite emulator
+type AzureClient struct {
+	AccountName string
+	AccountKey  string
+	Endpoint    string
+	HTTPClient  *http.Client
 }
 
-// NewClient :
+// Driver :
+func (AzureClient) Driver() string {
+	return AZURE
+}
+
+// NewClient : Create the adapter of the registered driver from the environment,
+// e.g. GOOGLE_APPLICATION_CREDENTIALS for GCS. ALIYUN and LOCAL need their client
+// and return ErrInvalidClient
 func NewClient(name string) *Builder {
 	builder := new(Builder)
-	if !client[strings.ToUpper(name)] {
-		builder.err = errors.New("The client not supported")
+
+	factory, err := lookupDriver(name)
+	if err != nil {
+		builder.err = err
+		return builder
 	}
 
-	switch strings.ToUpper(name) {
-	case GCS:
-		adapter := new(GCSAdapter)
-		builder.adapter = adapter
-		break
+	adapter, err := factory(nil)
+	if err != nil {
+		builder.err = err
+		return builder
 	}
+	builder.adapter = adapter
 
 	return builder
 }
 
-// New :
+// New : Create the adapter of the driver the client config belongs to, see Register
 func New(client interface{}) *Builder {
 	builder := new(Builder)
 
-	switch v := client.(type) {
-	case AliyunClient:
-		adapter := new(AliyunAdapter)
-		adapter.Endpoint = v.Endpoint
-		adapter.AccessKeyID = v.AccessKeyID
-		adapter.AccessKeySecret = v.AccessKeySecret
-		builder.adapter = adapter
+	// the value methods of the clients panic on a nil pointer
+	config, ok := client.(Config)
+	if value := reflect.ValueOf(client); ok && value.Kind() == reflect.Ptr && value.IsNil() {
+		builder.err = fmt.Errorf("%w: nil client %T", ErrInvalidClient, client)
+		return builder
+	}
+	if !ok {
+		builder.err = fmt.Errorf("%w: invalid client interface %T, available drivers: %s",
+			ErrInvalidClient, client, strings.Join(Drivers(), ", "))
+		return builder
+	}
 
-	case GoogleCloudStorageClient:
-		adapter := new(GCSAdapter)
-		builder.adapter = adapter
+	factory, err := lookupDriver(config.Driver())
+	if err != nil {
+		builder.err = err
+		return builder
+	}
 
-	default:
-		builder.err = errors.New("invalid client interface")
+	adapter, err := factory(config)
+	if err != nil {
+		builder.err = err
 		return builder
 	}
+	builder.adapter = adapter
 
 	return builder
 }
 
+// Close : Release the client held by the adapter, the builder should not be used afterwards
+func (b *Builder) Close() error {
+	if b.adapter == nil {
+		return b.err
+	}
+	return b.adapter.Close()
+}
+
+// List : Iterate over the objects of the bucket, see ListOptions for the filters
+func (b *Builder) List(ctx context.Context, bucket string, options ListOptions) *ObjectIterator {
+	it := &ObjectIterator{ctx: ctx, adapter: b.adapter, bucket: bucket, options: options}
+	if b.err != nil {
+		it.err = b.err
+		return it
+	}
+
+	if len(bucket) == 0 {
+		it.err = errors.New("storage: bucket is required")
+	}
+
+	return it
+}
+
+// Stat : Return the object attributes, or ErrNotFound when it doesn't exist
+func (b *Builder) Stat(bucket, name string) (*ObjectInfo, error) {
+	return b.StatWithContext(context.Background(), bucket, name)
+}
+
+// StatWithContext :
+func (b *Builder) StatWithContext(ctx context.Context, bucket, name string) (*ObjectInfo, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	var (
+		errNameIsRequired   = errors.New("storage: filename is required")
+		errBucketIsRequired = errors.New("storage: bucket is required")
+	)
+
+	if len(name) == 0 {
+		return nil, errNameIsRequired
+	}
+
+	if len(bucket) == 0 {
+		return nil, errBucketIsRequired
+	}
+
+	return b.adapter.Stat(ctx, bucket, name)
+}
+
+// Exists : Check the object exists without downloading it
+func (b *Builder) Exists(bucket, name string) (bool, error) {
+	return b.ExistsWithContext(context.Background(), bucket, name)
+}
+
+// ExistsWithContext :
+func (b *Builder) ExistsWithContext(ctx context.Context, bucket, name string) (bool, error) {
+	_, err := b.StatWithContext(ctx, bucket, name)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// OpenReader : Stream the object instead of keeping it in memory, the caller must close the reader
+func (b *Builder) OpenReader(bucket, name string) (io.ReadCloser, error) {
+	return b.OpenRangeReaderWithContext(context.Background(), bucket, name, 0, -1)
+}
+
+// OpenReaderWithContext :
+func (b *Builder) OpenReaderWithContext(ctx context.Context, bucket, name string) (io.ReadCloser, error) {
+	return b.OpenRangeReaderWithContext(ctx, bucket, name, 0, -1)
+}
+
+// OpenRangeReader : Stream length bytes from offset, a negative length reads until the end of the object
+func (b *Builder) OpenRangeReader(bucket, name string, offset, length int64) (io.ReadCloser, error) {
+	return b.OpenRangeReaderWithContext(context.Background(), bucket, name, offset, length)
+}
+
+// OpenRangeReaderWithContext :
+func (b *Builder) OpenRangeReaderWithContext(ctx context.Context, bucket, name string, offset, length int64) (io.ReadCloser, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	var (
+		errNameIsRequired   = errors.New("storage: filename is required")
+		errBucketIsRequired = errors.New("storage: bucket is required")
+		errInvalidOffset    = errors.New("storage: offset must not be negative")
+	)
+
+	if len(name) == 0 {
+		return nil, errNameIsRequired
+	}
+
+	if len(bucket) == 0 {
+		return nil, errBucketIsRequired
+	}
+
+	if offset < 0 {
+		return nil, errInvalidOffset
+	}
+
+	return b.adapter.OpenRangeReader(ctx, bucket, name, offset, length)
+}
+
 // UploadFile :
 func (b *Builder) UploadFile(file *multipart.FileHeader, bucket, name string) (string, error) {
+	return b.UploadFileWithContext(context.Background(), file, bucket, name)
+}
+
+// UploadFileWithContext :
+func (b *Builder) UploadFileWithContext(ctx context.Context, file *multipart.FileHeader, bucket, name string) (string, error) {
 	if b.err != nil {
 		return "", b.err
 	}
-	return b.adapter.UploadFile(file, bucket, name)
+	return b.adapter.UploadFileWithContext(ctx, file, bucket, name)
 }
 
 // ReadFile :
 func (b *Builder) ReadFile(bucket, path string) ([]byte, error) {
+	return b.ReadFileWithContext(context.Background(), bucket, path)
+}
+
+// ReadFileWithContext :
+func (b *Builder) ReadFileWithContext(ctx context.Context, bucket, path string) ([]byte, error) {
 	if b.err != nil {
 		return nil, b.err
 	}
-	return b.adapter.ReadFile(bucket, path)
+	return b.adapter.ReadFileWithContext(ctx, bucket, path)
 }
 
 // DeleteFileUsingURL :
 func (b *Builder) DeleteFileUsingURL(bucket, fileURL string) error {
+	return b.DeleteFileUsingURLWithContext(context.Background(), bucket, fileURL)
+}
+
+// DeleteFileUsingURLWithContext :
+func (b *Builder) DeleteFileUsingURLWithContext(ctx context.Context, bucket, fileURL string) error {
 	if b.err != nil {
 		return b.err
 	}
-	return b.adapter.DeleteFileUsingURL(bucket, fileURL)
+	return b.adapter.DeleteFileUsingURLWithContext(ctx, bucket, fileURL)
 }
 
 // TemporaryServingFile :
 func (b *Builder) TemporaryServingFile(bucket, fileURL string, expiredTime time.Time, client interface{}) (string, error) {
+	return b.TemporaryServingFileWithContext(context.Background(), bucket, fileURL, expiredTime, client)
+}
+
+// TemporaryServingFileWithContext :
+func (b *Builder) TemporaryServingFileWithContext(ctx context.Context, bucket, fileURL string, expiredTime time.Time, client interface{}) (string, error) {
 	if b.err != nil {
 		return "", b.err
 	}
-	return b.adapter.TemporaryServingFile(bucket, fileURL, expiredTime, client)
+	return b.adapter.TemporaryServingFileWithContext(ctx, bucket, fileURL, expiredTime, client)
+}
+
+// DeleteObject : Same as DeleteFileUsingURL, but using the key of the object.
+// Deleting a missing object succeeds on every adapter
+func (b *Builder) DeleteObject(bucket, key string) error {
+	return b.DeleteObjectWithContext(context.Background(), bucket, key)
+}
+
+// DeleteObjectWithContext :
+func (b *Builder) DeleteObjectWithContext(ctx context.Context, bucket, key string) error {
+	if b.err != nil {
+		return b.err
+	}
+	return b.adapter.DeleteObject(ctx, bucket, key)
+}
+
+// TemporaryServingObject : Same as TemporaryServingFile, but using the key of the object
+func (b *Builder) TemporaryServingObject(bucket, key string, expiredTime time.Time, client interface{}) (string, error) {
+	return b.TemporaryServingObjectWithContext(context.Background(), bucket, key, expiredTime, client)
+}
+
+// TemporaryServingObjectWithContext :
+func (b *Builder) TemporaryServingObjectWithContext(ctx context.Context, bucket, key string, expiredTime time.Time, client interface{}) (string, error) {
+	if b.err != nil {
+		return "", b.err
+	}
+	return b.adapter.TemporaryServingObject(ctx, bucket, key, expiredTime, client)
 }
 
 // GoogleTemporaryServingFile :
@@ -112,32 +350,40 @@ func GoogleTemporaryServingFile(bucket, fileURL string, expiredTime time.Time, c
 
 // UploadReader :
 func (b *Builder) UploadReader(bucket, filename string, reader io.Reader, contentType string) (string, error) {
-	if b.err != nil {
-		return "", b.err
-	}
-	var (
-		errNameIsRequired   = errors.New("storage: filename is required")
-		errBucketIsRequired = errors.New("storage: bucket is required")
-		errReaderIsNil      = errors.New("storage: io reader is nil")
-	)
+	return b.UploadReaderWithContext(context.Background(), bucket, filename, reader, contentType)
+}
 
-	if len(filename) == 0 {
-		return "", errNameIsRequired
+// UploadReaderWithContext :
+func (b *Builder) UploadReaderWithContext(ctx context.Context, bucket, filename string, reader io.Reader, contentType string) (string, error) {
+	result, err := b.Put(ctx, bucket, filename, reader, contentType)
+	if err != nil {
+		return "", err
 	}
 
-	if len(bucket) == 0 {
-		return "", errBucketIsRequired
-	}
+	return result.URL, nil
+}
 
-	if reader == nil {
-		return "", errReaderIsNil
-	}
+// Put : Same as UploadReader, but return the canonical identifiers of the object
+// instead of the url, so they can be stored
+func (b *Builder) Put(ctx context.Context, bucket, filename string, reader io.Reader, contentType string) (*UploadResult, error) {
+	return b.Upload(ctx, bucket, filename, reader, WithContentType(contentType))
+}
 
-	return b.adapter.UploadReader(bucket, filename, reader, contentType)
+// PutFile : Same as UploadFile, but return the canonical identifiers of the object
+func (b *Builder) PutFile(ctx context.Context, file *multipart.FileHeader, bucket, name string) (*UploadResult, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	return uploadMultipartFile(ctx, b.adapter, file, bucket, name)
 }
 
 // UploadBuffer :
 func (b *Builder) UploadBuffer(bucket, filename string, contentType string) (*Buffer, error) {
+	return b.UploadBufferWithContext(context.Background(), bucket, filename, contentType)
+}
+
+// UploadBufferWithContext : The context is kept by the buffer, cancelling it aborts the remaining writes
+func (b *Builder) UploadBufferWithContext(ctx context.Context, bucket, filename string, contentType string) (*Buffer, error) {
 	if b.err != nil {
 		return nil, b.err
 	}
@@ -154,5 +400,5 @@ func (b *Builder) UploadBuffer(bucket, filename string, contentType string) (*Bu
 		return nil, errBucketIsRequired
 	}
 
-	return b.adapter.UploadBuffer(bucket, filename, contentType)
+	return b.adapter.UploadBufferWithContext(ctx, bucket, filename, contentType)
 }
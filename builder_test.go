@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestAliyunClientIsShared(t *testing.T) {
+	adapter := &AliyunAdapter{Endpoint: "oss-cn-hangzhou.aliyuncs.com", AccessKeyID: "id", AccessKeySecret: "secret"}
+
+	first, err := adapter.getClient(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := adapter.getClient(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first != second {
+		t.Error("expected the oss client to be reused")
+	}
+
+	if err := adapter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	third, err := adapter.getClient(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if third == first {
+		t.Error("expected a new oss client after Close")
+	}
+}
+
+func TestBuilderClose(t *testing.T) {
+	adapter := NewMemoryAdapter()
+	builder := New(adapter)
+	if _, err := builder.UploadReader("bucket", "report.csv", strings.NewReader("a,b"), ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := builder.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, isExist := adapter.Object("bucket", "report.csv"); !isExist {
+		t.Error("expected the memory objects to be kept after Close")
+	}
+
+	if err := New("invalid").Close(); err == nil {
+		t.Error("expected closing a builder without an adapter to report its error")
+	}
+}
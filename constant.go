@@ -4,6 +4,10 @@ package storage
 const (
 	GCS    = "GCS"
 	ALIYUN = "ALIYUN"
+	LOCAL  = "LOCAL"
+	MEMORY = "MEMORY"
+	S3     = "S3"
+	AZURE  = "AZURE"
 )
 
 // Content Type
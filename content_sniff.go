@@ -0,0 +1,150 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// sniffLen : The head of the stream read for the detection, more than the 512
+// bytes of http.DetectContentType so the first entries of a zip are reached
+const sniffLen = 3072
+
+var zipSignature = []byte("PK\x03\x04")
+
+// heicBrands : The major brands of the ftyp box of the heic and heif images
+var heicBrands = map[string]bool{
+	"heic": true,
+	"heix": true,
+	"hevc": true,
+	"hevx": true,
+	"heim": true,
+	"heis": true,
+	"mif1": true,
+	"msf1": true,
+}
+
+// contentTypeKeys : The content type constant of the detected types, so the
+// mapper of the adapter sets the disposition as well
+var contentTypeKeys = map[string]string{
+	"text/csv":        ContentTypeCSV,
+	"image/png":       ContentTypePNG,
+	"image/jpeg":      ContentTypeJPEG,
+	"image/heic":      ContentTypeHEIC,
+	"application/pdf": ContentTypePDF,
+	"application/zip": ContentTypeZip,
+	"application/vnd.android.package-archive": ContentTypeAPK,
+	"text/html":                ContentTypeHTML,
+	"text/css":                 ContentTypeCSS,
+	"application/javascript":   ContentTypeJS,
+	"text/javascript":          ContentTypeJS,
+	"application/vnd.ms-excel": ContentTypeExcel,
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet": ContentTypeExcel,
+	"image/svg+xml": ContentTypeSVG,
+}
+
+// sniffContentType : The content type constant of the data, or of the extension of
+// the name when the data has no signature. A zip keeps the xlsx extension, the
+// entries of the workbook can be past the head. The detected content type is
+// returned as well when it has no constant
+func sniffContentType(filename string, head []byte) (string, string) {
+	filenameArr := strings.Split(filename, ".")
+	fileExtension := strings.TrimSpace(filenameArr[len(filenameArr)-1])
+
+	contentType := detectContentType(head)
+	if isGenericContentType(contentType) {
+		return fileExtension, ""
+	}
+
+	key, isExist := contentTypeKeys[contentTypeMediaType(contentType)]
+	if !isExist {
+		return "", contentType
+	}
+	if key == ContentTypeZip && fileExtension == ContentTypeExcel {
+		return fileExtension, ""
+	}
+
+	return key, ""
+}
+
+// peekContent : Read the head of the reader for the detection, the returned reader
+// still yields the whole stream
+func peekContent(reader io.Reader) ([]byte, io.Reader, error) {
+	br := bufio.NewReaderSize(reader, sniffLen)
+	head, err := br.Peek(sniffLen)
+	if err != nil && err != io.EOF {
+		return nil, nil, err
+	}
+
+	return head, br, nil
+}
+
+// detectContentType : http.DetectContentType, with the signatures it doesn't know
+// of, heic images, xlsx and apk archives and svg images
+func detectContentType(head []byte) string {
+	if len(head) > sniffLen {
+		head = head[:sniffLen]
+	}
+
+	if len(head) >= 12 && string(head[4:8]) == "ftyp" && heicBrands[string(head[8:12])] {
+		return "image/heic"
+	}
+
+	if bytes.HasPrefix(head, zipSignature) {
+		return zipContentType(head)
+	}
+
+	contentType := http.DetectContentType(head)
+	if isGenericContentType(contentType) || strings.HasPrefix(contentType, "text/xml") {
+		trimmed := bytes.TrimSpace(bytes.TrimPrefix(head, []byte("\xef\xbb\xbf")))
+		if bytes.HasPrefix(trimmed, []byte("<")) && bytes.Contains(bytes.ToLower(trimmed), []byte("<svg")) {
+			return "image/svg+xml"
+		}
+	}
+
+	return contentType
+}
+
+// zipContentType : Tell the zip based formats apart by the names of their entries,
+// a local file header is the signature, 26 bytes and the name of the entry
+func zipContentType(head []byte) string {
+	for i := 0; ; {
+		j := bytes.Index(head[i:], zipSignature)
+		if j < 0 || i+j+30 > len(head) {
+			break
+		}
+		i += j
+
+		start := i + 30
+		end := start + int(binary.LittleEndian.Uint16(head[i+26:]))
+		if end > len(head) {
+			break
+		}
+
+		name := string(head[start:end])
+		switch {
+		case name == "AndroidManifest.xml" || name == "classes.dex":
+			return "application/vnd.android.package-archive"
+		case strings.HasPrefix(name, "xl/"):
+			return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+		}
+		i = end
+	}
+
+	return "application/zip"
+}
+
+// isGenericContentType : The data has no known signature, the text formats, e.g.
+// csv, css or js, can only be told apart by their extension
+func isGenericContentType(contentType string) bool {
+	mediaType := contentTypeMediaType(contentType)
+	return mediaType == "" || mediaType == "application/octet-stream" || mediaType == "text/plain"
+}
+
+// contentTypeMediaType : The content type without its parameters, e.g. the charset
+func contentTypeMediaType(contentType string) string {
+	return strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+}
@@ -0,0 +1,177 @@
+package storage
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	s "cloud.google.com/go/storage"
+)
+
+var (
+	pngHead  = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	jpegHead = []byte("\xff\xd8\xff\xe0\x00\x10JFIF\x00")
+	heicHead = []byte("\x00\x00\x00\x18ftypheic\x00\x00\x00\x00")
+)
+
+const xlsxContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+func newZip(t *testing.T, names ...string) []byte {
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+	for _, name := range names {
+		if _, err := writer.Create(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDetectContentType(t *testing.T) {
+	tests := []struct {
+		name string
+		head []byte
+		want string
+	}{
+		{"png", pngHead, "image/png"},
+		{"jpeg", jpegHead, "image/jpeg"},
+		{"heic", heicHead, "image/heic"},
+		{"pdf", []byte("%PDF-1.4\n"), "application/pdf"},
+		{"zip", newZip(t, "report.csv"), "application/zip"},
+		{"xlsx", newZip(t, "[Content_Types].xml", "xl/workbook.xml"), xlsxContentType},
+		{"apk", newZip(t, "AndroidManifest.xml"), "application/vnd.android.package-archive"},
+		{"svg", []byte(`<?xml version="1.0"?><svg xmlns="http://www.w3.org/2000/svg"></svg>`), "image/svg+xml"},
+		{"csv", []byte("a,b\n1,2\n"), "text/plain; charset=utf-8"},
+		{"empty", nil, "text/plain; charset=utf-8"},
+	}
+
+	for _, tt := range tests {
+		if got := detectContentType(tt.head); got != tt.want {
+			t.Errorf("%s: detectContentType = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPeekContent(t *testing.T) {
+	data := bytes.Repeat([]byte("a,b\n"), sniffLen)
+	head, reader, err := peekContent(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(head) != sniffLen {
+		t.Errorf("expected a head of %d bytes, got %d", sniffLen, len(head))
+	}
+
+	// the peeked bytes are still read
+	if all, _ := ioutil.ReadAll(reader); !bytes.Equal(all, data) {
+		t.Errorf("expected the whole stream, got %d bytes", len(all))
+	}
+
+	if head, _, err := peekContent(strings.NewReader("a,b")); err != nil || string(head) != "a,b" {
+		t.Errorf("peek of a short stream returned %q, %v", head, err)
+	}
+}
+
+func TestContentTypeAnySniffsFirst(t *testing.T) {
+	tests := []struct {
+		name            string
+		head            []byte
+		wantType        string
+		wantDisposition string
+	}{
+		{"avatar", pngHead, "image/png", ""},
+		{"export", newZip(t, "data.json"), "application/zip", "attachment;filename=export"},
+		{"notes", []byte("plain text"), "", ""},
+		// the data has a signature, so the extension is ignored
+		{"report.csv", pngHead, "image/png", ""},
+		// text has no signature, so the extension tells the format
+		{"report.csv", []byte("a,b\n"), "text/csv", "attachment;filename=report.csv"},
+		{"report.csv", nil, "text/csv", "attachment;filename=report.csv"},
+		{"report.xlsx", newZip(t, "[Content_Types].xml", "xl/workbook.xml"), xlsxContentType, "attachment;filename=report.xlsx"},
+		// the entries of the workbook are past the head
+		{"report.xlsx", newZip(t, "docProps/app.xml"), xlsxContentType, "attachment;filename=report.xlsx"},
+	}
+
+	for _, tt := range tests {
+		sw := &s.Writer{}
+		sw.Name = tt.name
+		contentTypeAny(sw, tt.head)
+		if sw.ContentType != tt.wantType || sw.ContentDisposition != tt.wantDisposition {
+			t.Errorf("%s: got %q %q, want %q %q", tt.name, sw.ContentType, sw.ContentDisposition, tt.wantType, tt.wantDisposition)
+		}
+	}
+}
+
+func TestMemoryUploadSniffsTheData(t *testing.T) {
+	adapter := NewMemoryAdapter()
+	if _, err := adapter.Put(context.Background(), "bucket", "avatar", bytes.NewReader(pngHead), ""); err != nil {
+		t.Fatal(err)
+	}
+	if object, _ := adapter.Object("bucket", "avatar"); object.ContentType != "image/png" {
+		t.Errorf("expected the png to be detected, got %q", object.ContentType)
+	}
+}
+
+func TestUploadMultipartFileIsNamedAfterTheData(t *testing.T) {
+	tests := []struct {
+		contentType string
+		data        []byte
+		want        string
+	}{
+		// the header of the client is ignored when the data has a signature
+		{"text/plain", pngHead, "avatar.png"},
+		{"image/png", []byte("%PDF-1.4\n"), "avatar.pdf"},
+		{"", newZip(t, "AndroidManifest.xml"), "avatar.apk"},
+		// text has no signature, so the header tells the format
+		{"text/csv", []byte("a,b\n"), "avatar.csv"},
+		// the client extension is the last resort
+		{"image/png", []byte("a,b\n"), "avatar.png"},
+	}
+
+	for _, tt := range tests {
+		adapter := NewMemoryAdapter()
+		result, err := uploadMultipartFile(context.Background(), adapter, newFileHeader(t, tt.contentType, tt.data), "bucket", "avatar")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Key != tt.want {
+			t.Errorf("%q %q: uploaded as %q, want %q", tt.contentType, tt.data, result.Key, tt.want)
+		}
+	}
+}
+
+func TestAliyunUploadSniffsFirst(t *testing.T) {
+	var contentType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+	}))
+	defer server.Close()
+	adapter := &AliyunAdapter{Endpoint: server.URL, AccessKeyID: "id", AccessKeySecret: "secret"}
+
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"report.csv", pngHead, "image/png"},
+		{"report.csv", []byte("a,b\n"), "text/csv"},
+		{"animation", []byte("GIF89a"), "image/gif"},
+	}
+
+	for _, tt := range tests {
+		if _, err := adapter.Put(context.Background(), "bucket", tt.name, bytes.NewReader(tt.data), ""); err != nil {
+			t.Fatal(err)
+		}
+		if contentType != tt.want {
+			t.Errorf("%s %q: uploaded as %q, want %q", tt.name, tt.data, contentType, tt.want)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package storage
+
+import (
+	s "cloud.google.com/go/storage"
+)
+
+// resolveContentType : Resolve the content type and disposition through the
+// same mapper as GCS, for the adapters which have no writer of their own. The
+// head of the data is sniffed when there is no content type, it may be nil
+func resolveContentType(filename, contentType string, head []byte) (string, string, error) {
+	sw := new(s.Writer)
+	sw.Name = filename
+
+	if contentType == "" {
+		contentTypeAny(sw, head)
+	} else {
+		contentFunc, isExist := contentTypeMapper[contentType]
+		if !isExist {
+			return "", "", errUnsupportedContentType(contentType)
+		}
+		contentFunc(sw)
+	}
+
+	return sw.ContentType, sw.ContentDisposition, nil
+}
@@ -0,0 +1,103 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestContextReaderStopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	reader := newContextReader(ctx, strings.NewReader("abcdef"))
+
+	p := make([]byte, 3)
+	if n, err := reader.Read(p); err != nil || string(p[:n]) != "abc" {
+		t.Fatalf("read %q, %v", p[:n], err)
+	}
+
+	cancel()
+	if n, err := reader.Read(p); !errors.Is(err, context.Canceled) || n != 0 {
+		t.Errorf("read %d bytes after cancel, err %v", n, err)
+	}
+}
+
+func TestMemoryCancelledContext(t *testing.T) {
+	adapter := NewMemoryAdapter()
+	builder := New(adapter)
+	if _, err := builder.UploadReader("bucket", "report.csv", strings.NewReader("a,b"), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := builder.UploadReaderWithContext(ctx, "bucket", "other.csv", strings.NewReader("a,b"), ""); !errors.Is(err, context.Canceled) {
+		t.Errorf("upload: expected context.Canceled, got %v", err)
+	}
+	if _, isExist := adapter.Object("bucket", "other.csv"); isExist {
+		t.Error("an object is stored with a cancelled context")
+	}
+	if _, err := builder.ReadFileWithContext(ctx, "bucket", "report.csv"); !errors.Is(err, context.Canceled) {
+		t.Errorf("read: expected context.Canceled, got %v", err)
+	}
+	if err := builder.DeleteFileUsingURLWithContext(ctx, "bucket", "mem://bucket/report.csv"); !errors.Is(err, context.Canceled) {
+		t.Errorf("delete: expected context.Canceled, got %v", err)
+	}
+	if _, isExist := adapter.Object("bucket", "report.csv"); !isExist {
+		t.Error("the object is deleted with a cancelled context")
+	}
+	if _, err := builder.UploadBufferWithContext(ctx, "bucket", "log.csv", ""); !errors.Is(err, context.Canceled) {
+		t.Errorf("buffer: expected context.Canceled, got %v", err)
+	}
+}
+
+func TestBufferKeepsTheContext(t *testing.T) {
+	adapter := NewMemoryAdapter()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	buf, err := New(adapter).UploadBufferWithContext(ctx, "bucket", "log.csv", ContentTypeCSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := buf.CopyString("a,b\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	cancel()
+	if err := buf.CopyString("1,2\n"); err == nil {
+		t.Error("expected a write after cancel to fail")
+	}
+	if object, _ := adapter.Object("bucket", "log.csv"); string(object.Data) != "a,b\n" {
+		t.Errorf("read %q after cancel", object.Data)
+	}
+}
+
+func TestLocalCancelledUploadLeavesNoFile(t *testing.T) {
+	adapter, cleanup := newLocalTestAdapter(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	reader := &cancelAfterReader{reader: strings.NewReader("a,b\n1,2\n"), cancel: cancel}
+
+	if _, err := adapter.UploadReaderWithContext(ctx, "bucket", "report.csv", reader, ""); err == nil {
+		t.Fatal("expected the upload to fail once the context is cancelled")
+	}
+	if _, err := os.Stat(filepath.Join(adapter.Root, "bucket", "report.csv")); !os.IsNotExist(err) {
+		t.Errorf("a partial file is left behind: %v", err)
+	}
+}
+
+// cancelAfterReader : Cancel the context after the first read
+type cancelAfterReader struct {
+	reader *strings.Reader
+	cancel context.CancelFunc
+}
+
+func (r *cancelAfterReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p[:1])
+	r.cancel()
+	return n, err
+}
@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// CopyOptions : Override the attributes of the copy, the empty ones are copied
+// from the source. A non nil Metadata replaces the metadata of the source
+type CopyOptions struct {
+	ContentType string
+	Metadata    map[string]string
+}
+
+// Copier : Implemented by the adapters which copy on the provider side, so the
+// data doesn't go through the process
+type Copier interface {
+	Copy(ctx context.Context, src, dst ObjectRef, options CopyOptions) (*UploadResult, error)
+}
+
+// mover : Implemented by the adapters which can rename an object atomically
+type mover interface {
+	move(ctx context.Context, src, dst ObjectRef, options CopyOptions) (*UploadResult, error)
+}
+
+var errCopyOptionsNotSupported = fmt.Errorf("%w: the adapter keeps no attributes to override", ErrUnsupported)
+
+var _ Copier = &GCSAdapter{}
+var _ Copier = &AliyunAdapter{}
+var _ Copier = &LocalAdapter{}
+var _ Copier = &MemoryAdapter{}
+var _ Copier = &S3Adapter{}
+var _ Copier = &AzureBlobAdapter{}
+
+// Copy : Copy the object on the provider side, between keys or buckets of the
+// same provider
+func (b *Builder) Copy(src, dst ObjectRef, options CopyOptions) (*UploadResult, error) {
+	return b.CopyWithContext(context.Background(), src, dst, options)
+}
+
+// CopyWithContext :
+func (b *Builder) CopyWithContext(ctx context.Context, src, dst ObjectRef, options CopyOptions) (*UploadResult, error) {
+	copier, err := b.copier(src, dst)
+	if err != nil {
+		return nil, err
+	}
+
+	return copier.Copy(ctx, src, dst, options)
+}
+
+// Move : Copy the object then delete the source. The providers have no rename, so
+// a failed delete leaves both objects, except on the local file system where the
+// file is renamed
+func (b *Builder) Move(src, dst ObjectRef, options CopyOptions) (*UploadResult, error) {
+	return b.MoveWithContext(context.Background(), src, dst, options)
+}
+
+// MoveWithContext :
+func (b *Builder) MoveWithContext(ctx context.Context, src, dst ObjectRef, options CopyOptions) (*UploadResult, error) {
+	copier, err := b.copier(src, dst)
+	if err != nil {
+		return nil, err
+	}
+
+	if m, ok := copier.(mover); ok {
+		return m.move(ctx, src, dst, options)
+	}
+
+	result, err := copier.Copy(ctx, src, dst, options)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := b.adapter.DeleteObject(ctx, src.Bucket, src.Key); err != nil {
+		return result, err
+	}
+
+	return result, nil
+}
+
+func (b *Builder) copier(src, dst ObjectRef) (Copier, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	var (
+		errSourceIsRequired      = errors.New("storage: source bucket and key are required")
+		errDestinationIsRequired = errors.New("storage: destination bucket and key are required")
+		errSameObject            = errors.New("storage: source and destination are the same object")
+	)
+
+	if len(src.Bucket) == 0 || len(src.Key) == 0 {
+		return nil, errSourceIsRequired
+	}
+
+	if len(dst.Bucket) == 0 || len(dst.Key) == 0 {
+		return nil, errDestinationIsRequired
+	}
+
+	if src.Provider != "" && dst.Provider != "" && src.Provider != dst.Provider {
+		return nil, fmt.Errorf("%w: can't copy from %s to %s", ErrUnsupported, src.Provider, dst.Provider)
+	}
+
+	if src.Bucket == dst.Bucket && src.Key == dst.Key {
+		return nil, errSameObject
+	}
+
+	copier, ok := b.adapter.(Copier)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T can't copy objects", ErrUnsupported, b.adapter)
+	}
+
+	return copier, nil
+}
+
+// isZero : The copy keeps every attribute of the source
+func (options CopyOptions) isZero() bool {
+	return options.ContentType == "" && options.Metadata == nil
+}
+
+// copyHeaders : The content headers and metadata of the source with the overrides applied
+func copyHeaders(source http.Header, metaPrefix string, options CopyOptions) http.Header {
+	return replaceHeaders(source, metaPrefix, options.ContentType, ObjectAttributes{Metadata: options.Metadata})
+}
+
+// copyResult : The result of a copy, from the attributes of the destination
+func copyResult(result *UploadResult, info *ObjectInfo) *UploadResult {
+	result.Size = info.Size
+	result.ContentType = info.ContentType
+	result.ETag = info.ETag
+	result.MD5 = info.MD5
+	result.CRC32C = info.CRC32C
+	return result
+}
@@ -0,0 +1,144 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestCopyIsValidated(t *testing.T) {
+	builder := New(NewMemoryAdapter())
+	src := ObjectRef{Bucket: "bucket", Key: "report.csv"}
+
+	tests := []struct {
+		src, dst ObjectRef
+	}{
+		{ObjectRef{Bucket: "bucket"}, ObjectRef{Bucket: "bucket", Key: "copy.csv"}},
+		{src, ObjectRef{Key: "copy.csv"}},
+		{src, src},
+		{ObjectRef{Provider: "gcs", Bucket: "bucket", Key: "report.csv"}, ObjectRef{Provider: "aliyun", Bucket: "bucket", Key: "copy.csv"}},
+	}
+	for _, tt := range tests {
+		if _, err := builder.Copy(tt.src, tt.dst, CopyOptions{}); err == nil {
+			t.Errorf("%v to %v: expected the copy to be refused", tt.src, tt.dst)
+		}
+	}
+
+	if _, err := builder.Copy(src, ObjectRef{Bucket: "bucket", Key: "copy.csv"}, CopyOptions{}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestMemoryCopyAndMove(t *testing.T) {
+	adapter := NewMemoryAdapter()
+	builder := New(adapter)
+	if _, err := builder.UploadReader("bucket", "report.csv", strings.NewReader("a,b"), ContentTypeCSV); err != nil {
+		t.Fatal(err)
+	}
+	src := ObjectRef{Bucket: "bucket", Key: "report.csv"}
+
+	result, err := builder.Copy(src, ObjectRef{Bucket: "archive", Key: "report.csv"}, CopyOptions{Metadata: map[string]string{"tenant": "a"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Size != 3 || result.ContentType != "text/csv" {
+		t.Errorf("unexpected result %+v", result)
+	}
+	object, _ := adapter.Object("archive", "report.csv")
+	if string(object.Data) != "a,b" || object.Metadata["tenant"] != "a" {
+		t.Errorf("unexpected copy %+v", object)
+	}
+
+	if _, err := builder.Move(src, ObjectRef{Bucket: "bucket", Key: "moved.csv"}, CopyOptions{ContentType: "text/plain"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, isExist := adapter.Object("bucket", "report.csv"); isExist {
+		t.Error("expected the source to be deleted")
+	}
+	if object, _ := adapter.Object("bucket", "moved.csv"); object.ContentType != "text/plain" {
+		t.Errorf("expected the content type override, got %q", object.ContentType)
+	}
+}
+
+func TestLocalCopyAndMove(t *testing.T) {
+	adapter, cleanup := newLocalTestAdapter(t)
+	defer cleanup()
+	builder := New(LocalClient{Root: adapter.Root})
+	if _, err := builder.UploadReader("bucket", "report.csv", strings.NewReader("a,b"), ""); err != nil {
+		t.Fatal(err)
+	}
+	src := ObjectRef{Bucket: "bucket", Key: "report.csv"}
+
+	if _, err := builder.Copy(src, ObjectRef{Bucket: "bucket", Key: "copy.csv"}, CopyOptions{ContentType: "text/csv"}); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("expected the overrides to be refused, got %v", err)
+	}
+	if _, err := builder.Copy(src, ObjectRef{Bucket: "archive", Key: "dir/copy.csv"}, CopyOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := builder.ReadFile("archive", "dir/copy.csv"); err != nil || string(data) != "a,b" {
+		t.Errorf("read %q, %v from the copy", data, err)
+	}
+
+	result, err := builder.Move(src, ObjectRef{Bucket: "bucket", Key: "moved/report.csv"}, CopyOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Size != 3 {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if _, err := builder.ReadFile("bucket", "report.csv"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected the source to be renamed, got %v", err)
+	}
+}
+
+func TestS3Copy(t *testing.T) {
+	fake, adapter, stop := newFakeS3(t)
+	defer stop()
+	fake.objects["report.csv"] = []byte("a,b")
+
+	var directive string
+	fake.handler = func(w http.ResponseWriter, r *http.Request, body []byte) bool {
+		key := strings.TrimPrefix(r.URL.Path, "/bucket/")
+		switch {
+		case r.Method == http.MethodHead:
+			data, isExist := fake.objects[key]
+			if !isExist {
+				w.WriteHeader(http.StatusNotFound)
+				return true
+			}
+			w.Header().Set("Content-Type", "text/csv")
+			w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+			w.Header().Set("X-Amz-Meta-Tenant", "a")
+			return true
+		case r.Header.Get("X-Amz-Copy-Source") == "/bucket/missing.csv":
+			// the failure of a copy comes after the 200 status
+			w.Write([]byte("<Error><Code>InternalError</Code><Message>copy failed</Message></Error>"))
+			return true
+		case r.Header.Get("X-Amz-Copy-Source") != "":
+			directive = r.Header.Get("X-Amz-Metadata-Directive")
+			fake.objects[key] = fake.objects[strings.TrimPrefix(r.Header.Get("X-Amz-Copy-Source"), "/bucket/")]
+			w.Write([]byte("<CopyObjectResult><ETag>\"etag\"</ETag></CopyObjectResult>"))
+			return true
+		}
+		return false
+	}
+
+	result, err := adapter.Copy(context.Background(), ObjectRef{Bucket: "bucket", Key: "report.csv"}, ObjectRef{Bucket: "bucket", Key: "copy.csv"}, CopyOptions{ContentType: "text/plain"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if directive != "REPLACE" || result.Size != 3 || string(fake.objects["copy.csv"]) != "a,b" {
+		t.Errorf("unexpected copy %+v, directive %q", result, directive)
+	}
+
+	if _, err := adapter.Copy(context.Background(), ObjectRef{Bucket: "bucket", Key: "missing.csv"}, ObjectRef{Bucket: "bucket", Key: "copy.csv"}, CopyOptions{}); err == nil {
+		t.Error("expected the error document to fail the copy")
+	}
+
+	if data, err := adapter.ReadFileWithContext(context.Background(), "bucket", "copy.csv"); err != nil || string(data) != "a,b" {
+		t.Errorf("read %q, %v from the copy", data, err)
+	}
+}
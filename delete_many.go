@@ -0,0 +1,187 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+const (
+	// deleteBatchSize : The most keys a batch delete request accepts, on aliyun and s3
+	deleteBatchSize = 1000
+
+	// deleteConcurrency : The deletes in flight for the adapters without batch delete
+	deleteConcurrency = 16
+)
+
+// DeleteError : The keys which could not be deleted, with the error of each
+type DeleteError struct {
+	Bucket string
+	Errors map[string]error
+}
+
+// Error : Summarize the failures with the first key in order
+func (e *DeleteError) Error() string {
+	keys := make([]string, 0, len(e.Errors))
+	for key := range e.Errors {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	if len(keys) == 1 {
+		return fmt.Sprintf("storage: could not delete %s/%s: %v", e.Bucket, keys[0], e.Errors[keys[0]])
+	}
+	return fmt.Sprintf("storage: could not delete %d objects of %s, first %s: %v", len(keys), e.Bucket, keys[0], e.Errors[keys[0]])
+}
+
+func (e *DeleteError) add(key string, err error) {
+	if e.Errors == nil {
+		e.Errors = make(map[string]error)
+	}
+	e.Errors[key] = err
+}
+
+// errorOrNil : Only return the error when a key failed
+func (e *DeleteError) errorOrNil() error {
+	if len(e.Errors) == 0 {
+		return nil
+	}
+	return e
+}
+
+// BatchDeleter : Implemented by the adapters which delete many keys per request.
+// The keys which don't exist are deleted successfully, and the failed keys are
+// returned in a *DeleteError
+type BatchDeleter interface {
+	DeleteMany(ctx context.Context, bucket string, keys []string) error
+}
+
+var _ BatchDeleter = &AliyunAdapter{}
+var _ BatchDeleter = &S3Adapter{}
+
+// DeleteMany : Delete the keys in batches where the provider supports it, in
+// parallel otherwise. Missing keys are not an error, the failed keys are returned
+// in a *DeleteError
+func (b *Builder) DeleteMany(bucket string, keys []string) error {
+	return b.DeleteManyWithContext(context.Background(), bucket, keys)
+}
+
+// DeleteManyWithContext :
+func (b *Builder) DeleteManyWithContext(ctx context.Context, bucket string, keys []string) error {
+	if b.err != nil {
+		return b.err
+	}
+	var errBucketIsRequired = errors.New("storage: bucket is required")
+
+	if len(bucket) == 0 {
+		return errBucketIsRequired
+	}
+
+	if len(keys) == 0 {
+		return nil
+	}
+
+	if deleter, ok := b.adapter.(BatchDeleter); ok {
+		return deleter.DeleteMany(ctx, bucket, keys)
+	}
+
+	return deleteParallel(ctx, b.adapter, bucket, keys)
+}
+
+// DeletePrefix : Delete every object whose key begins with the prefix, one listed
+// page at a time. The prefix is required, so a whole bucket is never emptied by
+// mistake. A listing error stops the deletion, the failed keys are returned in
+// a *DeleteError
+func (b *Builder) DeletePrefix(bucket, prefix string) error {
+	return b.DeletePrefixWithContext(context.Background(), bucket, prefix)
+}
+
+// DeletePrefixWithContext :
+func (b *Builder) DeletePrefixWithContext(ctx context.Context, bucket, prefix string) error {
+	if b.err != nil {
+		return b.err
+	}
+	var errPrefixIsRequired = errors.New("storage: prefix is required")
+
+	if len(prefix) == 0 {
+		return errPrefixIsRequired
+	}
+
+	failures := &DeleteError{Bucket: bucket}
+	it := b.List(ctx, bucket, ListOptions{Prefix: prefix, PageSize: deleteBatchSize})
+	for {
+		page, err := it.NextPage()
+		if err == ErrIteratorDone {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		keys := make([]string, 0, len(page.Objects))
+		for _, object := range page.Objects {
+			keys = append(keys, object.Name)
+		}
+
+		err = b.DeleteManyWithContext(ctx, bucket, keys)
+		var deleteError *DeleteError
+		if errors.As(err, &deleteError) {
+			for key, err := range deleteError.Errors {
+				failures.add(key, err)
+			}
+		} else if err != nil {
+			return err
+		}
+	}
+
+	return failures.errorOrNil()
+}
+
+// deleteParallel : Delete the keys one per request, with deleteConcurrency in flight
+func deleteParallel(ctx context.Context, adapter Adapter, bucket string, keys []string) error {
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		failures = &DeleteError{Bucket: bucket}
+		jobs     = make(chan string)
+	)
+
+	workers := deleteConcurrency
+	if len(keys) < workers {
+		workers = len(keys)
+	}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for key := range jobs {
+				err := adapter.DeleteObject(ctx, bucket, key)
+				if err != nil && !errors.Is(err, ErrNotFound) {
+					mu.Lock()
+					failures.add(key, err)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	for _, key := range keys {
+		jobs <- key
+	}
+	close(jobs)
+	wg.Wait()
+
+	return failures.errorOrNil()
+}
+
+// deleteBatches : Split the keys in batches of deleteBatchSize
+func deleteBatches(keys []string) [][]string {
+	batches := make([][]string, 0, (len(keys)+deleteBatchSize-1)/deleteBatchSize)
+	for len(keys) > deleteBatchSize {
+		batches = append(batches, keys[:deleteBatchSize])
+		keys = keys[deleteBatchSize:]
+	}
+	return append(batches, keys)
+}
@@ -0,0 +1,113 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// lockedAdapter : Refuse to delete the locked keys
+type lockedAdapter struct {
+	*MemoryAdapter
+	locked map[string]bool
+}
+
+func (adapter *lockedAdapter) DeleteObject(ctx context.Context, bucket, key string) error {
+	if adapter.locked[key] {
+		return memoryError("delete", bucket, key, ErrPermissionDenied)
+	}
+	return adapter.MemoryAdapter.DeleteObject(ctx, bucket, key)
+}
+
+func TestDeleteManyIsValidated(t *testing.T) {
+	builder := New(NewMemoryAdapter())
+
+	if err := builder.DeleteMany("", []string{"report.csv"}); err == nil {
+		t.Error("expected the bucket to be required")
+	}
+	if err := builder.DeleteMany("bucket", nil); err != nil {
+		t.Errorf("expected no keys to be a no-op, got %v", err)
+	}
+	if err := builder.DeletePrefix("bucket", ""); err == nil {
+		t.Error("expected the prefix to be required")
+	}
+}
+
+func TestDeleteMany(t *testing.T) {
+	adapter := NewMemoryAdapter()
+	builder := New(adapter)
+	keys := []string{"missing.csv"}
+	for i := 0; i < 40; i++ {
+		key := "report-" + strconv.Itoa(i) + ".csv"
+		if _, err := builder.UploadReader("bucket", key, strings.NewReader("a,b"), ContentTypeCSV); err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, key)
+	}
+
+	// the missing keys are deleted successfully
+	if err := builder.DeleteMany("bucket", keys); err != nil {
+		t.Fatal(err)
+	}
+	if objects := adapter.Objects("bucket"); len(objects) != 0 {
+		t.Errorf("expected every object to be deleted, got %v", objects)
+	}
+}
+
+func TestDeleteParallelReturnsTheFailedKeys(t *testing.T) {
+	adapter := &lockedAdapter{MemoryAdapter: NewMemoryAdapter(), locked: map[string]bool{"a.csv": true, "c.csv": true}}
+	for _, key := range []string{"a.csv", "b.csv", "c.csv"} {
+		if _, err := adapter.Put(context.Background(), "bucket", key, strings.NewReader("a,b"), ContentTypeCSV); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := deleteParallel(context.Background(), adapter, "bucket", []string{"a.csv", "b.csv", "c.csv"})
+	var deleteErr *DeleteError
+	if !errors.As(err, &deleteErr) {
+		t.Fatalf("expected a DeleteError, got %v", err)
+	}
+	if len(deleteErr.Errors) != 2 || !errors.Is(deleteErr.Errors["a.csv"], ErrPermissionDenied) || !errors.Is(deleteErr.Errors["c.csv"], ErrPermissionDenied) {
+		t.Errorf("expected a.csv and c.csv to fail, got %v", deleteErr.Errors)
+	}
+	if !strings.Contains(err.Error(), "2 objects of bucket, first a.csv") {
+		t.Errorf("unexpected message %q", err)
+	}
+	if _, isExist := adapter.Object("bucket", "b.csv"); isExist {
+		t.Error("expected b.csv to be deleted")
+	}
+}
+
+func TestDeletePrefix(t *testing.T) {
+	adapter := NewMemoryAdapter()
+	bucket := New(adapter).Bucket("bucket")
+	for _, key := range []string{"tmp/a.csv", "tmp/dir/b.csv", "tmpfile.csv", "keep/c.csv"} {
+		if _, err := bucket.Upload(context.Background(), key, strings.NewReader("a,b"), WithContentType(ContentTypeCSV)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := bucket.DeletePrefix(context.Background(), "tmp/"); err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, object := range adapter.Objects("bucket") {
+		names = append(names, object.Name)
+	}
+	sort.Strings(names)
+	if strings.Join(names, ",") != "keep/c.csv,tmpfile.csv" {
+		t.Errorf("unexpected objects left %v", names)
+	}
+}
+
+func TestDeleteBatches(t *testing.T) {
+	keys := make([]string, 2*deleteBatchSize+1)
+	batches := deleteBatches(keys)
+	if len(batches) != 3 || len(batches[0]) != deleteBatchSize || len(batches[2]) != 1 {
+		t.Errorf("unexpected batches of %d keys", len(batches))
+	}
+}
@@ -0,0 +1,252 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	s "cloud.google.com/go/storage"
+	oss "github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"google.golang.org/api/googleapi"
+)
+
+// Provider neutral errors, match them with errors.Is
+var (
+	ErrNotFound               = errors.New("storage: object not found")
+	ErrPermissionDenied       = errors.New("storage: permission denied")
+	ErrPreconditionFailed     = errors.New("storage: precondition failed")
+	ErrConflict               = errors.New("storage: conflict with the current state of the object")
+	ErrThrottled              = errors.New("storage: request throttled")
+	ErrUnsupportedContentType = errors.New("storage: unsupported content type")
+	ErrInvalidClient          = errors.New("storage: invalid client")
+	ErrUnsupported            = errors.New("storage: operation not supported by the adapter")
+	ErrChecksumMismatch       = errors.New("storage: checksum mismatch")
+)
+
+// StorageError : Carry the failed operation and the original sdk error, which
+// is still reachable through errors.As
+type StorageError struct {
+	Provider string
+	Op       string
+	Bucket   string
+	Key      string
+	Err      error
+
+	kind error
+}
+
+func (e *StorageError) Error() string {
+	return fmt.Sprintf("storage: %s %s %s/%s: %v", e.Provider, e.Op, e.Bucket, e.Key, e.Err)
+}
+
+// Unwrap : Return the original sdk error
+func (e *StorageError) Unwrap() error {
+	return e.Err
+}
+
+// Is : Match the provider neutral error the sdk error was translated into
+func (e *StorageError) Is(target error) bool {
+	return e.kind != nil && e.kind == target
+}
+
+func newStorageError(provider, op, bucket, key string, err, kind error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(*StorageError); ok {
+		return err
+	}
+
+	return &StorageError{
+		Provider: provider,
+		Op:       op,
+		Bucket:   bucket,
+		Key:      key,
+		Err:      err,
+		kind:     kind,
+	}
+}
+
+func errUnsupportedContentType(contentType string) error {
+	return fmt.Errorf("%w: %s", ErrUnsupportedContentType, contentType)
+}
+
+// neutralError : Return the provider neutral error err already wraps, if any
+func neutralError(err error) error {
+	for _, kind := range []error{
+		ErrNotFound,
+		ErrPermissionDenied,
+		ErrPreconditionFailed,
+		ErrConflict,
+		ErrThrottled,
+		ErrUnsupportedContentType,
+		ErrInvalidClient,
+		ErrUnsupported,
+		ErrChecksumMismatch,
+	} {
+		if errors.Is(err, kind) {
+			return kind
+		}
+	}
+
+	return nil
+}
+
+// httpStatusError : Translate the status code shared by every provider
+func httpStatusError(statusCode int) error {
+	switch statusCode {
+	case http.StatusNotFound:
+		return ErrNotFound
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return ErrPermissionDenied
+	case http.StatusPreconditionFailed:
+		return ErrPreconditionFailed
+	case http.StatusConflict:
+		return ErrConflict
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		return ErrThrottled
+	}
+
+	return nil
+}
+
+func gcsError(op, bucket, key string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	// the upload errors are wrapped, so the sdk errors are matched with errors.Is and errors.As
+	var kind error
+	var e *googleapi.Error
+	if errors.Is(err, s.ErrObjectNotExist) || errors.Is(err, s.ErrBucketNotExist) {
+		kind = ErrNotFound
+	} else if errors.As(err, &e) {
+		kind = httpStatusError(e.Code)
+		// the md5 of the writer is refused with a bad request, told apart by its message
+		if e.Code == http.StatusBadRequest && strings.Contains(e.Message, "MD5") {
+			kind = ErrChecksumMismatch
+		}
+	} else {
+		kind = neutralError(err)
+	}
+
+	return newStorageError(GCS, op, bucket, key, err, kind)
+}
+
+func aliyunError(op, bucket, key string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var kind error
+	var e oss.ServiceError
+	if errors.As(err, &e) {
+		switch e.Code {
+		case "NoSuchKey", "NoSuchBucket":
+			kind = ErrNotFound
+		case "AccessDenied", "InvalidAccessKeyId", "SignatureDoesNotMatch", "SecurityTokenExpired":
+			kind = ErrPermissionDenied
+		case "PreconditionFailed", "FileAlreadyExists", "PositionNotEqualToLength":
+			kind = ErrPreconditionFailed
+		case "RequestThrottled", "SlowDown":
+			kind = ErrThrottled
+		case "InvalidDigest", "BadDigest":
+			kind = ErrChecksumMismatch
+		default:
+			// the head requests have no body, so there is no code to match
+			kind = httpStatusError(e.StatusCode)
+		}
+	} else {
+		kind = neutralError(err)
+	}
+
+	return newStorageError(ALIYUN, op, bucket, key, err, kind)
+}
+
+func localError(op, bucket, key string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var kind error
+	if errors.Is(err, os.ErrNotExist) {
+		kind = ErrNotFound
+	} else if errors.Is(err, os.ErrPermission) {
+		kind = ErrPermissionDenied
+	} else {
+		kind = neutralError(err)
+	}
+
+	return newStorageError(LOCAL, op, bucket, key, err, kind)
+}
+
+func memoryError(op, bucket, key string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return newStorageError(MEMORY, op, bucket, key, err, neutralError(err))
+}
+
+func s3Error(op, bucket, key string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var kind error
+	var e *S3ServiceError
+	if errors.As(err, &e) {
+		switch e.Code {
+		case "NoSuchKey", "NoSuchBucket", "NoSuchUpload":
+			kind = ErrNotFound
+		case "AccessDenied", "InvalidAccessKeyId", "SignatureDoesNotMatch", "ExpiredToken", "InvalidToken":
+			kind = ErrPermissionDenied
+		case "PreconditionFailed":
+			kind = ErrPreconditionFailed
+		case "ConditionalRequestConflict", "OperationAborted":
+			kind = ErrConflict
+		case "SlowDown", "Throttling", "RequestLimitExceeded":
+			kind = ErrThrottled
+		case "InvalidDigest", "BadDigest":
+			kind = ErrChecksumMismatch
+		default:
+			// the head requests have no body, so there is no code to match
+			kind = httpStatusError(e.StatusCode)
+		}
+	} else {
+		kind = neutralError(err)
+	}
+
+	return newStorageError(S3, op, bucket, key, err, kind)
+}
+
+func azureError(op, container, key string, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var kind error
+	var e *AzureServiceError
+	if errors.As(err, &e) {
+		switch e.Code {
+		case "BlobNotFound", "ContainerNotFound", "ResourceNotFound":
+			kind = ErrNotFound
+		case "AuthenticationFailed", "AuthorizationFailure", "AuthorizationPermissionMismatch", "InsufficientAccountPermissions":
+			kind = ErrPermissionDenied
+		case "ConditionNotMet", "BlobAlreadyExists", "TargetConditionNotMet":
+			kind = ErrPreconditionFailed
+		case "ServerBusy", "OperationTimedOut":
+			kind = ErrThrottled
+		case "Md5Mismatch", "InvalidMd5":
+			kind = ErrChecksumMismatch
+		default:
+			kind = httpStatusError(e.StatusCode)
+		}
+	} else {
+		kind = neutralError(err)
+	}
+
+	return newStorageError(AZURE, op, container, key, err, kind)
+}
@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	s "cloud.google.com/go/storage"
+	oss "github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"google.golang.org/api/googleapi"
+)
+
+func TestProviderErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"gcs object", gcsError("read", "bucket", "key", s.ErrObjectNotExist), ErrNotFound},
+		{"gcs bucket", gcsError("read", "bucket", "key", s.ErrBucketNotExist), ErrNotFound},
+		{"gcs forbidden", gcsError("read", "bucket", "key", &googleapi.Error{Code: http.StatusForbidden}), ErrPermissionDenied},
+		{"gcs precondition", gcsError("upload", "bucket", "key", &googleapi.Error{Code: http.StatusPreconditionFailed}), ErrPreconditionFailed},
+		{"gcs conflict", gcsError("upload", "bucket", "key", &googleapi.Error{Code: http.StatusConflict}), ErrConflict},
+		{"gcs throttled", gcsError("upload", "bucket", "key", &googleapi.Error{Code: http.StatusTooManyRequests}), ErrThrottled},
+		{"aliyun key", aliyunError("read", "bucket", "key", oss.ServiceError{Code: "NoSuchKey", StatusCode: http.StatusNotFound}), ErrNotFound},
+		{"aliyun denied", aliyunError("read", "bucket", "key", oss.ServiceError{Code: "AccessDenied", StatusCode: http.StatusForbidden}), ErrPermissionDenied},
+		{"aliyun append", aliyunError("upload", "bucket", "key", oss.ServiceError{Code: "PositionNotEqualToLength", StatusCode: http.StatusConflict}), ErrPreconditionFailed},
+		{"aliyun head", aliyunError("stat", "bucket", "key", oss.ServiceError{StatusCode: http.StatusNotFound}), ErrNotFound},
+		{"s3 conflict", s3Error("upload", "bucket", "key", &S3ServiceError{Code: "ConditionalRequestConflict", StatusCode: http.StatusConflict}), ErrConflict},
+		{"azure exists", azureError("upload", "bucket", "key", &AzureServiceError{Code: "BlobAlreadyExists", StatusCode: http.StatusConflict}), ErrPreconditionFailed},
+		{"azure lease", azureError("upload", "bucket", "key", &AzureServiceError{Code: "LeaseIdMissing", StatusCode: http.StatusConflict}), ErrConflict},
+		{"local missing", localError("read", "bucket", "key", &os.PathError{Op: "open", Path: "/data/key", Err: os.ErrNotExist}), ErrNotFound},
+		{"local permission", localError("read", "bucket", "key", &os.PathError{Op: "open", Path: "/data/key", Err: os.ErrPermission}), ErrPermissionDenied},
+		{"memory", memoryError("read", "bucket", "key", ErrNotFound), ErrNotFound},
+		{"content type", memoryError("upload", "bucket", "key", errUnsupportedContentType("msword")), ErrUnsupportedContentType},
+	}
+
+	for _, tt := range tests {
+		if !errors.Is(tt.err, tt.want) {
+			t.Errorf("%s: %v is not %v", tt.name, tt.err, tt.want)
+		}
+
+		var storageErr *StorageError
+		if !errors.As(tt.err, &storageErr) {
+			t.Errorf("%s: expected a *StorageError, got %T", tt.name, tt.err)
+			continue
+		}
+		if storageErr.Bucket != "bucket" || storageErr.Key != "key" {
+			t.Errorf("%s: unexpected error %+v", tt.name, storageErr)
+		}
+	}
+}
+
+func TestStorageErrorKeepsTheSDKError(t *testing.T) {
+	sdkErr := oss.ServiceError{Code: "SlowDown", StatusCode: http.StatusServiceUnavailable}
+	err := aliyunError("upload", "bucket", "key", sdkErr)
+
+	if !errors.Is(err, ErrThrottled) {
+		t.Errorf("%v is not ErrThrottled", err)
+	}
+	var serviceErr oss.ServiceError
+	if !errors.As(err, &serviceErr) || serviceErr.Code != "SlowDown" {
+		t.Errorf("expected the oss error to be reachable, got %v", err)
+	}
+	if errors.Is(err, ErrNotFound) {
+		t.Error("a throttled error must not match ErrNotFound")
+	}
+	if !strings.Contains(err.Error(), "upload bucket/key") {
+		t.Errorf("the message misses the operation: %q", err.Error())
+	}
+
+	// an error translated twice keeps the first translation
+	if again := aliyunError("read", "other", "other", err); again != err {
+		t.Errorf("expected the error to be kept, got %v", again)
+	}
+	if gcsError("read", "bucket", "key", nil) != nil {
+		t.Error("expected a nil error to stay nil")
+	}
+}
+
+func TestUnknownErrorHasNoKind(t *testing.T) {
+	err := gcsError("read", "bucket", "key", errors.New("connection reset"))
+	for _, kind := range []error{ErrNotFound, ErrPermissionDenied, ErrPreconditionFailed, ErrThrottled} {
+		if errors.Is(err, kind) {
+			t.Errorf("%v matches %v", err, kind)
+		}
+	}
+}
+
+func TestUnsupportedContentTypeMessage(t *testing.T) {
+	if got, want := errUnsupportedContentType("msword").Error(), "storage: unsupported content type: msword"; got != want {
+		t.Errorf("message = %q, want %q", got, want)
+	}
+}
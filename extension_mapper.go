@@ -3,8 +3,10 @@ package storage
 // Content Type
 const (
 	ExtensionAPK = "vnd.android.package-archive"
+	ExtensionSVG = "svg+xml"
 )
 
 var extensionMapper = map[string]string{
 	ExtensionAPK: "apk",
+	ExtensionSVG: "svg",
 }
@@ -1,16 +1,27 @@
 package storage
 
 import (
+	"bytes"
 	"context"
-	"errors"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"mime/multipart"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	s "cloud.google.com/go/storage"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+	htransport "google.golang.org/api/transport/http"
 )
 
 const (
@@ -31,60 +42,244 @@ type GoogleClient struct {
 	ClientX509CertURL       string `json:"client_x509_cert_url"`
 }
 
-// GCSAdapter : The adapter empty because they do not have any data to share for now
-type GCSAdapter struct{}
+// GCSAdapter : The storage client is created once and shared by every operation,
+// it is safe for concurrent use. The credential is taken from Credential, then
+// CredentialsJSON, then CredentialsFile, and the default credentials are used
+// when they are all empty. Endpoint is the root of the server, e.g.
+// http://localhost:4443 for fake-gcs-server, which needs no credentials
+type GCSAdapter struct {
+	Credential      *GoogleClient
+	CredentialsJSON []byte
+	CredentialsFile string
+	Endpoint        string
+
+	mu          sync.Mutex
+	client      *s.Client
+	mediaClient *http.Client
+}
+
+func init() {
+	Register(GCS, func(config Config) (Adapter, error) {
+		var client GoogleCloudStorageClient
+		switch config := config.(type) {
+		case nil:
+			// the environment
+		case GoogleCloudStorageClient:
+			client = config
+		case *GoogleCloudStorageClient:
+			if config == nil {
+				return nil, invalidConfig(GCS, config)
+			}
+			client = *config
+		default:
+			return nil, invalidConfig(GCS, config)
+		}
+
+		adapter := new(GCSAdapter)
+		adapter.Credential = client.Credential
+		adapter.CredentialsJSON = client.CredentialsJSON
+		adapter.CredentialsFile = client.CredentialsFile
+		adapter.Endpoint = client.Endpoint
+		if _, err := adapter.getClient(); err != nil {
+			return nil, err
+		}
+		return adapter, nil
+	})
+}
 
 // UploadFile : Upload file to the bucket
 func (adapter *GCSAdapter) UploadFile(file *multipart.FileHeader, bucket, filename string) (string, error) {
-	var reader io.Reader
-	name := ""
+	return adapter.UploadFileWithContext(context.Background(), file, bucket, filename)
+}
 
-	fileExt := strings.ToLower(strings.Split(file.Header["Content-Type"][0], "/")[1])
+// UploadFileWithContext : Upload file to the bucket
+func (adapter *GCSAdapter) UploadFileWithContext(ctx context.Context, file *multipart.FileHeader, bucket, filename string) (string, error) {
+	result, err := uploadMultipartFile(ctx, adapter, file, bucket, filename)
+	if err != nil {
+		return "", err
+	}
 
-	if ext, isExist := extensionMapper[fileExt]; isExist {
-		name = fmt.Sprintf("%s.%s", filename, ext)
-	} else {
-		name = fmt.Sprintf("%s.%s", filename, fileExt)
+	return result.URL, nil
+}
+
+// DeleteFileUsingURL : Delete file from the bucket using url
+func (adapter *GCSAdapter) DeleteFileUsingURL(bucket, fileURL string) error {
+	return adapter.DeleteFileUsingURLWithContext(context.Background(), bucket, fileURL)
+}
+
+// DeleteFileUsingURLWithContext : Delete file from the bucket using url
+func (adapter *GCSAdapter) DeleteFileUsingURLWithContext(ctx context.Context, bucket, fileURL string) error {
+	key, err := adapter.objectKey(bucket, fileURL)
+	if err != nil {
+		return gcsError("delete", bucket, fileURL, err)
 	}
 
-	src, err := file.Open()
+	return adapter.DeleteObject(ctx, bucket, key)
+}
+
+// DeleteObject : Delete the object by its key
+func (adapter *GCSAdapter) DeleteObject(ctx context.Context, bucket, key string) error {
+	storageClient, err := adapter.getClient()
 	if err != nil {
-		return "", err
+		return err
+	}
+
+	// deleting a missing object succeeds, same as the other providers
+	err = storageClient.Bucket(bucket).Object(key).Delete(ctx)
+	if err == s.ErrObjectNotExist {
+		return nil
 	}
 
-	defer src.Close()
-	reader = src
+	return gcsError("delete", bucket, key, err)
+}
+
+// UpdateMetadata : Patch the attributes, a nil Metadata keeps the current ones.
+// A patch merges the metadata, so the current keys missing from the new map are
+// set to "", as long as the object didn't change meanwhile. An empty map deletes
+// the whole metadata
+func (adapter *GCSAdapter) UpdateMetadata(ctx context.Context, bucket, key string, attrs ObjectAttributes) error {
+	if attrs.Tags != nil {
+		return gcsError("update", bucket, key, errTagsNotSupported("gcs"))
+	}
 
-	fileURL, err := adapter.UploadReader(bucket, name, reader, strings.ToLower(fileExt))
+	storageClient, err := adapter.getClient()
 	if err != nil {
-		return "", err
+		return gcsError("update", bucket, key, err)
 	}
 
-	return fileURL, nil
+	object := storageClient.Bucket(bucket).Object(key)
+	update := s.ObjectAttrsToUpdate{}
+	switch {
+	case attrs.Metadata == nil:
+	case len(attrs.Metadata) == 0:
+		update.Metadata = map[string]string{}
+	default:
+		current, err := object.Attrs(ctx)
+		if err != nil {
+			return gcsError("update", bucket, key, err)
+		}
+
+		metadata := make(map[string]string, len(attrs.Metadata)+len(current.Metadata))
+		for k := range current.Metadata {
+			metadata[k] = ""
+		}
+		for k, v := range attrs.Metadata {
+			metadata[k] = v
+		}
+		update.Metadata = metadata
+		object = object.If(s.Conditions{MetagenerationMatch: current.Metageneration})
+	}
+	if attrs.CacheControl != "" {
+		update.CacheControl = attrs.CacheControl
+	}
+	if attrs.ContentEncoding != "" {
+		update.ContentEncoding = attrs.ContentEncoding
+	}
+	if attrs.ContentLanguage != "" {
+		update.ContentLanguage = attrs.ContentLanguage
+	}
+
+	_, err = object.Update(ctx, update)
+	return gcsError("update", bucket, key, err)
 }
 
-// DeleteFileUsingURL : Delete file from the bucket using url
-func (adapter *GCSAdapter) DeleteFileUsingURL(bucket, fileURL string) error {
-	ctx := context.Background()
-	storageClient, err := s.NewClient(ctx)
+// Copy : Copy with a rewrite, which may take several calls for large objects.
+// GCS replaces the attributes as soon as one is given, so the others are carried
+// over from the source
+func (adapter *GCSAdapter) Copy(ctx context.Context, src, dst ObjectRef, options CopyOptions) (*UploadResult, error) {
+	storageClient, err := adapter.getClient()
 	if err != nil {
-		return err
+		return nil, gcsError("copy", src.Bucket, src.Key, err)
 	}
-	defer storageClient.Close()
 
-	fileName := strings.Replace(fileURL, fmt.Sprintf("%s/%s/", googleGCSDomain, bucket), "", -1)
+	source := storageClient.Bucket(src.Bucket).Object(src.Key)
+	copier := storageClient.Bucket(dst.Bucket).Object(dst.Key).CopierFrom(source)
+
+	if !options.isZero() {
+		attrs, err := source.Attrs(ctx)
+		if err != nil {
+			return nil, gcsError("copy", src.Bucket, src.Key, err)
+		}
+
+		copier.ContentType = attrs.ContentType
+		copier.ContentDisposition = attrs.ContentDisposition
+		copier.ContentEncoding = attrs.ContentEncoding
+		copier.ContentLanguage = attrs.ContentLanguage
+		copier.CacheControl = attrs.CacheControl
+		copier.Metadata = attrs.Metadata
+		if options.ContentType != "" {
+			copier.ContentType = options.ContentType
+		}
+		if options.Metadata != nil {
+			copier.Metadata = options.Metadata
+		}
+	}
 
-	return storageClient.Bucket(bucket).Object(fileName).Delete(ctx)
+	attrs, err := copier.Run(ctx)
+	if err != nil {
+		return nil, gcsError("copy", src.Bucket, src.Key, err)
+	}
+
+	return adapter.uploadResult(attrs), nil
 }
 
 // TemporaryServingFile : TemporaryServingFile file serving
 func (adapter *GCSAdapter) TemporaryServingFile(bucket, fileURL string, expiredDateTime time.Time, googleClient interface{}) (string, error) {
-	credential := googleClient.(GoogleClient)
+	return adapter.TemporaryServingFileWithContext(context.Background(), bucket, fileURL, expiredDateTime, googleClient)
+}
+
+// TemporaryServingFileWithContext : TemporaryServingFile file serving
+func (adapter *GCSAdapter) TemporaryServingFileWithContext(ctx context.Context, bucket, fileURL string, expiredDateTime time.Time, googleClient interface{}) (string, error) {
+	key, err := adapter.objectKey(bucket, fileURL)
+	if err != nil {
+		return "", gcsError("sign", bucket, fileURL, err)
+	}
+
+	return adapter.TemporaryServingObject(ctx, bucket, key, expiredDateTime, googleClient)
+}
+
+// TemporaryServingObject : Sign the object by its key, the url is signed locally so
+// the context is only checked before signing. A nil client signs with the
+// credential of the adapter
+func (adapter *GCSAdapter) TemporaryServingObject(ctx context.Context, bucket, key string, expiredDateTime time.Time, googleClient interface{}) (string, error) {
+	return adapter.TemporaryServingObjectWithOptions(ctx, bucket, key, expiredDateTime, googleClient, ResponseOptions{})
+}
+
+// TemporaryServingObjectWithOptions : Sign the object by its key, the response
+// overrides are signed with V4 signing, which expires within 7 days. GCS has no
+// cache control override
+func (adapter *GCSAdapter) TemporaryServingObjectWithOptions(ctx context.Context, bucket, key string, expiredDateTime time.Time, googleClient interface{}, options ResponseOptions) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	credential, err := adapter.signingCredential(googleClient)
+	if err != nil {
+		return "", gcsError("sign", bucket, key, err)
+	}
+
+	if options != (ResponseOptions{}) {
+		if options.CacheControl != "" {
+			return "", gcsError("sign", bucket, key, fmt.Errorf("%w: gcs can't override the cache control of a signed url", ErrUnsupported))
+		}
+
+		u, err := url.Parse(adapter.endpoint())
+		if err != nil {
+			return "", gcsError("sign", bucket, key, err)
+		}
+		u.Path = "/" + bucket + "/" + key
+		u.RawQuery = options.query().Encode()
+
+		signedURL, err := gcsSignV4(credential, http.MethodGet, u, nil, expiredDateTime, time.Now())
+		if err != nil {
+			return "", gcsError("sign", bucket, key, err)
+		}
+		return signedURL, nil
+	}
 
 	method := "GET"
 
-	fileName := strings.Replace(fileURL, fmt.Sprintf("%s/%s/", googleGCSDomain, bucket), "", -1)
-	url, err := s.SignedURL(bucket, fileName, &s.SignedURLOptions{
+	url, err := s.SignedURL(bucket, key, &s.SignedURLOptions{
 		GoogleAccessID: credential.ClientEmail,
 		PrivateKey:     []byte(credential.PrivateKey),
 		Method:         method,
@@ -92,81 +287,537 @@ func (adapter *GCSAdapter) TemporaryServingFile(bucket, fileURL string, expiredD
 	})
 
 	if err != nil {
-		return "", err
+		return "", gcsError("sign", bucket, key, err)
+	}
+
+	// the sdk always signs for storage.googleapis.com
+	if adapter.Endpoint != "" {
+		url = adapter.endpoint() + strings.TrimPrefix(url, googleGCSDomain)
 	}
 
 	return url, nil
 }
 
+// signingCredential : The credential of the client, a nil client signs with the
+// credential of the adapter
+func (adapter *GCSAdapter) signingCredential(googleClient interface{}) (GoogleClient, error) {
+	if googleClient == nil {
+		credential, err := adapter.credential()
+		if err != nil {
+			return GoogleClient{}, err
+		}
+		if credential != nil {
+			googleClient = *credential
+		}
+	} else if credential, ok := googleClient.(*GoogleClient); ok && credential != nil {
+		googleClient = *credential
+	}
+
+	credential, ok := googleClient.(GoogleClient)
+	if !ok {
+		return GoogleClient{}, fmt.Errorf("%w: expected GoogleClient, got %T", ErrInvalidClient, googleClient)
+	}
+
+	return credential, nil
+}
+
+// SignedUploadURL : Sign a PUT url of the object with V4 signing, every header is
+// signed and the max size is enforced with x-goog-content-length-range
+func (adapter *GCSAdapter) SignedUploadURL(ctx context.Context, bucket, key string, options SignOptions) (*SignedRequest, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, gcsError("sign", bucket, key, err)
+	}
+
+	credential, err := adapter.credential()
+	if err != nil {
+		return nil, gcsError("sign", bucket, key, err)
+	}
+	if credential == nil {
+		return nil, gcsError("sign", bucket, key, fmt.Errorf("%w: a service account credential is required to sign", ErrInvalidClient))
+	}
+
+	u, err := url.Parse(adapter.endpoint())
+	if err != nil {
+		return nil, gcsError("sign", bucket, key, err)
+	}
+	u.Path = "/" + bucket + "/" + key
+
+	header := options.uploadHeaders()
+	if options.MaxSize > 0 {
+		header.Set("X-Goog-Content-Length-Range", fmt.Sprintf("0,%d", options.MaxSize))
+	}
+
+	signedURL, err := gcsSignV4(*credential, http.MethodPut, u, header, options.Expires, time.Now())
+	if err != nil {
+		return nil, gcsError("sign", bucket, key, err)
+	}
+
+	return &SignedRequest{Method: http.MethodPut, URL: signedURL, Headers: header}, nil
+}
+
+// PostPolicy : Sign a V4 policy of a form upload with the credential of the adapter
+func (adapter *GCSAdapter) PostPolicy(ctx context.Context, bucket, key string, options PolicyOptions) (*PostForm, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, gcsError("sign", bucket, key, err)
+	}
+
+	credential, err := adapter.credential()
+	if err != nil {
+		return nil, gcsError("sign", bucket, key, err)
+	}
+	if credential == nil {
+		return nil, gcsError("sign", bucket, key, fmt.Errorf("%w: a service account credential is required to sign", ErrInvalidClient))
+	}
+
+	form, err := gcsPostPolicyV4(*credential, adapter.endpoint(), bucket, key, options, time.Now())
+	if err != nil {
+		return nil, gcsError("sign", bucket, key, err)
+	}
+
+	return form, nil
+}
+
 // UploadReader :
 func (adapter *GCSAdapter) UploadReader(bucket, filename string, reader io.Reader, contentType string) (string, error) {
+	return adapter.UploadReaderWithContext(context.Background(), bucket, filename, reader, contentType)
+}
 
-	ctx := context.Background()
-	storageClient, err := s.NewClient(ctx)
+// UploadReaderWithContext :
+func (adapter *GCSAdapter) UploadReaderWithContext(ctx context.Context, bucket, filename string, reader io.Reader, contentType string) (string, error) {
+	result, err := adapter.Put(ctx, bucket, filename, reader, contentType)
 	if err != nil {
 		return "", err
 	}
 
-	sw := storageClient.Bucket(bucket).Object(filename).NewWriter(ctx)
+	return result.URL, nil
+}
 
-	if contentType == "" {
-		contentTypeAny(sw)
+// Put : Upload the reader and return the canonical identifiers of the object
+func (adapter *GCSAdapter) Put(ctx context.Context, bucket, filename string, reader io.Reader, contentType string) (*UploadResult, error) {
+	return adapter.PutWithAttributes(ctx, bucket, filename, reader, contentType, ObjectAttributes{})
+}
+
+// PutWithAttributes : Same as Put, the metadata are set as x-goog-meta-* and gcs has no tags
+func (adapter *GCSAdapter) PutWithAttributes(ctx context.Context, bucket, filename string, reader io.Reader, contentType string, attrs ObjectAttributes) (*UploadResult, error) {
+	return adapter.Upload(ctx, bucket, filename, reader, UploadOptions{ContentType: contentType, Attributes: attrs})
+}
+
+// Upload : The options are set on the fields of the storage.Writer, the md5 is
+// checked by gcs and IfNotExists is the DoesNotExist condition of the object
+func (adapter *GCSAdapter) Upload(ctx context.Context, bucket, filename string, reader io.Reader, options UploadOptions) (*UploadResult, error) {
+	if options.Attributes.Tags != nil {
+		return nil, gcsError("upload", bucket, filename, errTagsNotSupported("gcs"))
+	}
+
+	storageClient, err := adapter.getClient()
+	if err != nil {
+		return nil, err
+	}
+
+	object := storageClient.Bucket(bucket).Object(filename)
+	if options.IfNotExists {
+		object = object.If(s.Conditions{DoesNotExist: true})
+	}
+	sw := object.NewWriter(ctx)
+
+	if options.ContentType == "" {
+		head, peeked, err := peekContent(reader)
+		if err != nil {
+			msg := fmt.Errorf("Could not read file: %w", err)
+			return nil, gcsError("upload", bucket, filename, msg)
+		}
+		reader = peeked
+		contentTypeAny(sw, head)
 	} else {
-		contentFunc, isExist := contentTypeMapper[contentType]
+		contentFunc, isExist := contentTypeMapper[options.ContentType]
 		if !isExist {
-			return "", fmt.Errorf("Content type %s does not supported", contentType)
+			return nil, gcsError("upload", bucket, filename, errUnsupportedContentType(options.ContentType))
 		}
 		contentFunc(sw)
 	}
+	if options.ContentDisposition != "" {
+		sw.ContentDisposition = options.ContentDisposition
+	}
+	sw.Metadata = options.Attributes.Metadata
+	sw.CacheControl = options.Attributes.CacheControl
+	sw.ContentEncoding = options.Attributes.ContentEncoding
+	sw.ContentLanguage = options.Attributes.ContentLanguage
+	sw.MD5 = options.MD5
+	sw.PredefinedACL = gcsPredefinedACL(options.ACL)
+	sw.StorageClass = options.StorageClass
 
 	if _, err := io.Copy(sw, reader); err != nil {
-		msg := fmt.Sprintf("Could not write file: %v", err)
-		return "", errors.New(msg)
+		msg := fmt.Errorf("Could not write file: %w", err)
+		return nil, gcsError("upload", bucket, filename, msg)
 	}
 
 	if err := sw.Close(); err != nil {
-		msg := fmt.Sprintf("Could not put file: %v", err)
-		return "", errors.New(msg)
+		msg := fmt.Errorf("Could not put file: %w", err)
+		return nil, gcsError("upload", bucket, filename, msg)
 	}
 
-	return fmt.Sprintf("%s/%s/%s", googleGCSDomain, bucket, filename), nil
+	return adapter.uploadResult(sw.Attrs()), nil
 }
 
 // ReadFile :
 func (adapter *GCSAdapter) ReadFile(bucket, path string) ([]byte, error) {
-	ctx := context.Background()
-	client, err := s.NewClient(ctx)
+	return adapter.ReadFileWithContext(context.Background(), bucket, path)
+}
+
+// ReadFileWithContext : Convenience wrapper over OpenReader, the whole object is kept in memory
+func (adapter *GCSAdapter) ReadFileWithContext(ctx context.Context, bucket, path string) ([]byte, error) {
+	rc, err := adapter.OpenRangeReader(ctx, bucket, path, 0, -1)
 	if err != nil {
 		return nil, err
 	}
-	defer client.Close()
-	rc, err := client.Bucket(bucket).Object(path).NewReader(ctx)
+	defer rc.Close()
+
+	return ioutil.ReadAll(rc)
+}
+
+// OpenRangeReader : Read length bytes from offset through NewRangeReader, a negative
+// length reads until the end of the object
+func (adapter *GCSAdapter) OpenRangeReader(ctx context.Context, bucket, path string, offset, length int64) (io.ReadCloser, error) {
+	storageClient, err := adapter.getClient()
 	if err != nil {
 		return nil, err
 	}
-	defer rc.Close()
-	slurp, err := ioutil.ReadAll(rc)
+
+	if adapter.Endpoint != "" {
+		rc, err := adapter.openMedia(ctx, bucket, path, offset, length)
+		if err != nil {
+			return nil, gcsError("read", bucket, path, err)
+		}
+		return rc, nil
+	}
+
+	rc, err := storageClient.Bucket(bucket).Object(path).NewRangeReader(ctx, offset, length)
 	if err != nil {
-		return nil, err
+		return nil, gcsError("read", bucket, path, err)
 	}
-	return slurp, nil
+
+	return rc, nil
 }
 
 // UploadBuffer :
 func (adapter *GCSAdapter) UploadBuffer(bucket, filename string, contentType string) (*Buffer, error) {
+	return adapter.UploadBufferWithContext(context.Background(), bucket, filename, contentType)
+}
+
+// UploadBufferWithContext :
+func (adapter *GCSAdapter) UploadBufferWithContext(ctx context.Context, bucket, filename string, contentType string) (*Buffer, error) {
 	buf := new(Buffer)
 	buf.adapter = GCS
+	buf.ctx = ctx
 
-	ctx := context.Background()
-	storageClient, err := s.NewClient(ctx)
+	storageClient, err := adapter.getClient()
 	if err != nil {
 		return nil, err
 	}
 
 	sw := storageClient.Bucket(bucket).Object(filename).NewWriter(ctx)
+	if contentType == "" {
+		contentTypeAny(sw, nil)
+	} else {
+		contentFunc, isExist := contentTypeMapper[contentType]
+		if !isExist {
+			return nil, gcsError("upload", bucket, filename, errUnsupportedContentType(contentType))
+		}
+		contentFunc(sw)
+	}
+
 	buf.storageWriter = sw
+	buf.gcs = adapter
+	buf.filename = filename
 	buf.bucket = bucket
-	contentTypeMapper[contentType](sw)
 
 	return buf, nil
 }
+
+// List : Return one page of the objects through Bucket.Objects
+func (adapter *GCSAdapter) List(ctx context.Context, bucket string, options ListOptions) (*ListResult, error) {
+	storageClient, err := adapter.getClient()
+	if err != nil {
+		return nil, err
+	}
+
+	pageSize := options.PageSize
+	if pageSize <= 0 {
+		pageSize = defaultListPageSize
+	}
+
+	it := storageClient.Bucket(bucket).Objects(ctx, &s.Query{
+		Prefix:    options.Prefix,
+		Delimiter: options.Delimiter,
+	})
+
+	attrs := make([]*s.ObjectAttrs, 0)
+	token, err := iterator.NewPager(it, pageSize, options.PageToken).NextPage(&attrs)
+	if err != nil {
+		return nil, gcsError("list", bucket, options.Prefix, err)
+	}
+
+	result := new(ListResult)
+	result.NextPageToken = token
+	for _, attr := range attrs {
+		result.Objects = append(result.Objects, &ObjectInfo{
+			Name:        attr.Name,
+			Size:        attr.Size,
+			ContentType: attr.ContentType,
+			Updated:     attr.Updated,
+			Prefix:      attr.Prefix,
+		})
+	}
+
+	return result, nil
+}
+
+// Stat : Return the object attributes through ObjectHandle.Attrs. The sdk doesn't
+// expose the etag, so the hex md5 is used instead, empty for composite objects
+func (adapter *GCSAdapter) Stat(ctx context.Context, bucket, filename string) (*ObjectInfo, error) {
+	storageClient, err := adapter.getClient()
+	if err != nil {
+		return nil, err
+	}
+
+	attrs, err := storageClient.Bucket(bucket).Object(filename).Attrs(ctx)
+	if err != nil {
+		return nil, gcsError("stat", bucket, filename, err)
+	}
+
+	return &ObjectInfo{
+		Name:               attrs.Name,
+		Size:               attrs.Size,
+		ContentType:        attrs.ContentType,
+		ContentDisposition: attrs.ContentDisposition,
+		ContentEncoding:    attrs.ContentEncoding,
+		ContentLanguage:    attrs.ContentLanguage,
+		CacheControl:       attrs.CacheControl,
+		ETag:               hex.EncodeToString(attrs.MD5),
+		MD5:                attrs.MD5,
+		CRC32C:             attrs.CRC32C,
+		Created:            attrs.Created,
+		Updated:            attrs.Updated,
+		Metadata:           attrs.Metadata,
+	}, nil
+}
+
+// Close : Release the storage client, the adapter creates a new one if it is used again
+func (adapter *GCSAdapter) Close() error {
+	adapter.mu.Lock()
+	defer adapter.mu.Unlock()
+
+	if adapter.client == nil {
+		return nil
+	}
+
+	err := adapter.client.Close()
+	adapter.client = nil
+	if adapter.mediaClient != nil {
+		adapter.mediaClient.CloseIdleConnections()
+		adapter.mediaClient = nil
+	}
+	return err
+}
+
+func (adapter *GCSAdapter) uploadResult(attrs *s.ObjectAttrs) *UploadResult {
+	return &UploadResult{
+		Bucket:      attrs.Bucket,
+		Key:         attrs.Name,
+		URL:         fmt.Sprintf("%s/%s/%s", adapter.endpoint(), attrs.Bucket, escapeObjectKey(attrs.Name)),
+		Size:        attrs.Size,
+		ContentType: attrs.ContentType,
+		ETag:        hex.EncodeToString(attrs.MD5),
+		MD5:         attrs.MD5,
+		CRC32C:      attrs.CRC32C,
+		Generation:  attrs.Generation,
+	}
+}
+
+func (adapter *GCSAdapter) getClient() (*s.Client, error) {
+	adapter.mu.Lock()
+	defer adapter.mu.Unlock()
+
+	if adapter.client != nil {
+		return adapter.client, nil
+	}
+
+	options, err := adapter.clientOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	// the client outlives the request, so it must not be bound to the request context
+	storageClient, err := s.NewClient(context.Background(), options...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidClient, err)
+	}
+
+	if adapter.Endpoint != "" {
+		options = append(options, option.WithScopes(s.ScopeFullControl))
+		mediaClient, _, err := htransport.NewClient(context.Background(), options...)
+		if err != nil {
+			storageClient.Close()
+			return nil, fmt.Errorf("%w: %v", ErrInvalidClient, err)
+		}
+		adapter.mediaClient = mediaClient
+	}
+	adapter.client = storageClient
+
+	return storageClient, nil
+}
+
+func (adapter *GCSAdapter) clientOptions() ([]option.ClientOption, error) {
+	options := make([]option.ClientOption, 0)
+
+	switch {
+	case adapter.Credential != nil:
+		data, err := json.Marshal(adapter.Credential)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidClient, err)
+		}
+		options = append(options, option.WithCredentialsJSON(data))
+	case len(adapter.CredentialsJSON) > 0:
+		options = append(options, option.WithCredentialsJSON(adapter.CredentialsJSON))
+	case adapter.CredentialsFile != "":
+		options = append(options, option.WithCredentialsFile(adapter.CredentialsFile))
+	case adapter.Endpoint != "":
+		// the emulators don't check the credentials
+		options = append(options, option.WithoutAuthentication())
+	}
+
+	if adapter.Endpoint != "" {
+		options = append(options, option.WithEndpoint(adapter.endpoint()+"/storage/v1/"))
+	}
+
+	return options, nil
+}
+
+// credential : The credential of the adapter for signing, nil when it uses the
+// default credentials
+func (adapter *GCSAdapter) credential() (*GoogleClient, error) {
+	switch {
+	case adapter.Credential != nil:
+		return adapter.Credential, nil
+	case len(adapter.CredentialsJSON) > 0:
+		credential := new(GoogleClient)
+		if err := json.Unmarshal(adapter.CredentialsJSON, credential); err != nil {
+			return nil, fmt.Errorf("%w: invalid google credential: %v", ErrInvalidClient, err)
+		}
+		return credential, nil
+	case adapter.CredentialsFile != "":
+		return readGoogleCredentialFile(adapter.CredentialsFile)
+	}
+
+	return nil, nil
+}
+
+// endpoint : The root of the server, without the json api path
+func (adapter *GCSAdapter) endpoint() string {
+	if adapter.Endpoint == "" {
+		return googleGCSDomain
+	}
+
+	endpoint := strings.TrimSuffix(adapter.Endpoint, "/")
+	return strings.TrimSuffix(endpoint, "/storage/v1")
+}
+
+// objectKey : Strip the endpoint of the adapter from the url, so the urls of a
+// custom endpoint are understood too
+func (adapter *GCSAdapter) objectKey(bucket, fileURL string) (string, error) {
+	prefix := adapter.endpoint() + "/" + bucket + "/"
+	if adapter.Endpoint != "" && strings.HasPrefix(fileURL, prefix) {
+		return objectKeyFromURL(bucket, googleGCSDomain+"/"+bucket+"/"+strings.TrimPrefix(fileURL, prefix))
+	}
+
+	return objectKeyFromURL(bucket, fileURL)
+}
+
+// openMedia : The reader of the sdk always downloads from storage.googleapis.com,
+// so the object is downloaded through the json api of the endpoint instead
+func (adapter *GCSAdapter) openMedia(ctx context.Context, bucket, path string, offset, length int64) (io.ReadCloser, error) {
+	if length == 0 {
+		return ioutil.NopCloser(bytes.NewReader(nil)), nil
+	}
+
+	adapter.mu.Lock()
+	mediaClient := adapter.mediaClient
+	adapter.mu.Unlock()
+	if mediaClient == nil {
+		// the adapter was closed after the caller got its client
+		return nil, fmt.Errorf("%w: the client is closed", ErrInvalidClient)
+	}
+
+	mediaURL := fmt.Sprintf("%s/storage/v1/b/%s/o/%s?alt=media", adapter.endpoint(), url.PathEscape(bucket), url.PathEscape(path))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, mediaURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if length > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+	} else if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := mediaClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		resp.Body.Close()
+		return nil, s.ErrObjectNotExist
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable:
+		resp.Body.Close()
+		return ioutil.NopCloser(bytes.NewReader(nil)), nil
+	case resp.StatusCode >= http.StatusMultipleChoices:
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(resp.Body)
+		return nil, &googleapi.Error{Code: resp.StatusCode, Body: string(body)}
+	}
+
+	return resp.Body, nil
+}
+
+// gcsPredefinedACL : The canned acl in the camel case of gcs, the other values are
+// already gcs predefined acl
+func gcsPredefinedACL(acl string) string {
+	switch acl {
+	case ACLPublicRead:
+		return "publicRead"
+	case "authenticated-read":
+		return "authenticatedRead"
+	case "bucket-owner-read":
+		return "bucketOwnerRead"
+	case "bucket-owner-full-control":
+		return "bucketOwnerFullControl"
+	}
+
+	return acl
+}
+
+// gcsBucketPattern : Lowercase letters, digits, dashes, underscores and dots, starting
+// and ending with a letter or digit
+var gcsBucketPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*[a-z0-9]$`)
+
+// validateBucket : 3 to 63 characters, or up to 222 when the name has dots with 63
+// characters at most between them. The names can't start with "goog", contain
+// "google" or be an ip address
+func (adapter *GCSAdapter) validateBucket(bucket string) error {
+	if len(bucket) < 3 || len(bucket) > 222 || !gcsBucketPattern.MatchString(bucket) {
+		return errInvalidBucketName(bucket)
+	}
+
+	for _, label := range strings.Split(bucket, ".") {
+		if len(label) == 0 || len(label) > 63 {
+			return errInvalidBucketName(bucket)
+		}
+	}
+
+	if strings.HasPrefix(bucket, "goog") || strings.Contains(bucket, "google") || net.ParseIP(bucket) != nil {
+		return errInvalidBucketName(bucket)
+	}
+
+	return nil
+}
@@ -0,0 +1,311 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeGCS : The json api of a single bucket, enough for the adapter with a custom
+// endpoint, e.g. fake-gcs-server
+type fakeGCS struct {
+	t       *testing.T
+	mu      sync.Mutex
+	objects map[string]map[string]interface{}
+	data    map[string][]byte
+}
+
+func newFakeGCS(t *testing.T) (*fakeGCS, *GCSAdapter, func()) {
+	fake := &fakeGCS{t: t, objects: make(map[string]map[string]interface{}), data: make(map[string][]byte)}
+	server := httptest.NewServer(fake)
+	return fake, &GCSAdapter{Endpoint: server.URL}, server.Close
+}
+
+func (fake *fakeGCS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+
+	const objects = "/storage/v1/b/bucket/o"
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == objects && r.URL.Query().Get("uploadType") == "multipart":
+		fake.upload(w, r)
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, objects+"/"):
+		name := strings.TrimPrefix(r.URL.Path, objects+"/")
+		attrs, isExist := fake.objects[name]
+		if !isExist {
+			http.Error(w, `{"error":{"code":404,"message":"Not Found"}}`, http.StatusNotFound)
+			return
+		}
+		if r.URL.Query().Get("alt") == "media" {
+			fake.media(w, r, fake.data[name])
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(attrs)
+	case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, objects+"/"):
+		name := strings.TrimPrefix(r.URL.Path, objects+"/")
+		if _, isExist := fake.objects[name]; !isExist {
+			http.Error(w, `{"error":{"code":404,"message":"Not Found"}}`, http.StatusNotFound)
+			return
+		}
+		delete(fake.objects, name)
+		delete(fake.data, name)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		fake.t.Errorf("unexpected %s %s", r.Method, r.URL)
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+// upload : The first part of the body is the resource, the second the data
+func (fake *fakeGCS) upload(w http.ResponseWriter, r *http.Request) {
+	attrs := make(map[string]interface{})
+	data, err := readGCSMultipart(r, attrs)
+	if err != nil {
+		fake.t.Errorf("invalid upload: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	name, _ := attrs["name"].(string)
+	attrs["bucket"] = "bucket"
+	attrs["size"] = fmt.Sprint(len(data))
+	fake.objects[name] = attrs
+	fake.data[name] = data
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(attrs)
+}
+
+func readGCSMultipart(r *http.Request, attrs map[string]interface{}) ([]byte, error) {
+	_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, err
+	}
+	reader := multipart.NewReader(r.Body, params["boundary"])
+
+	part, err := reader.NextPart()
+	if err != nil {
+		return nil, err
+	}
+	if err := json.NewDecoder(part).Decode(&attrs); err != nil {
+		return nil, err
+	}
+
+	part, err = reader.NextPart()
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(part)
+}
+
+func (fake *fakeGCS) media(w http.ResponseWriter, r *http.Request, data []byte) {
+	var start, end int
+	if n, _ := fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-%d", &start, &end); n > 0 {
+		if start >= len(data) {
+			w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+			return
+		}
+		if n == 1 || end >= len(data) {
+			end = len(data) - 1
+		}
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write(data[start : end+1])
+		return
+	}
+	w.Write(data)
+}
+
+func TestGCSCustomEndpoint(t *testing.T) {
+	ctx := context.Background()
+	fake, adapter, stop := newFakeGCS(t)
+	defer stop()
+
+	result, err := adapter.Put(ctx, "bucket", "dir/report.csv", strings.NewReader("a,b\n1,2\n"), ContentTypeCSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := adapter.Endpoint + "/bucket/dir/report.csv"; result.URL != want {
+		t.Errorf("URL = %q, want %q", result.URL, want)
+	}
+	if fake.objects["dir/report.csv"]["contentType"] != "text/csv" {
+		t.Errorf("unexpected resource %v", fake.objects["dir/report.csv"])
+	}
+
+	info, err := adapter.Stat(ctx, "bucket", "dir/report.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size != 8 || info.ContentType != "text/csv" {
+		t.Errorf("unexpected info %+v", info)
+	}
+
+	data, err := adapter.ReadFileWithContext(ctx, "bucket", "dir/report.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "a,b\n1,2\n" {
+		t.Errorf("read %q", data)
+	}
+
+	for _, tt := range []struct {
+		offset, length int64
+		want           string
+	}{
+		{4, 3, "1,2"},
+		{6, -1, "2\n"},
+		{20, -1, ""},
+	} {
+		rc, err := adapter.OpenRangeReader(ctx, "bucket", "dir/report.csv", tt.offset, tt.length)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := ioutil.ReadAll(rc)
+		rc.Close()
+		if string(data) != tt.want {
+			t.Errorf("range %d,%d = %q, want %q", tt.offset, tt.length, data, tt.want)
+		}
+	}
+
+	if _, err := adapter.ReadFileWithContext(ctx, "bucket", "missing.csv"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	if err := adapter.DeleteFileUsingURLWithContext(ctx, "bucket", result.URL); err != nil {
+		t.Fatal(err)
+	}
+	if _, isExist := fake.objects["dir/report.csv"]; isExist {
+		t.Error("the object is not deleted through its url")
+	}
+}
+
+func TestGCSCredential(t *testing.T) {
+	credential := &GoogleClient{Type: "service_account", ClientEmail: "a@project.iam.gserviceaccount.com"}
+
+	adapter := &GCSAdapter{Credential: credential, CredentialsJSON: []byte(`{"client_email":"b@project.iam.gserviceaccount.com"}`)}
+	if got, err := adapter.credential(); err != nil || got.ClientEmail != credential.ClientEmail {
+		t.Errorf("expected the credential to take precedence, got %+v, %v", got, err)
+	}
+
+	adapter = &GCSAdapter{CredentialsJSON: []byte(`{"client_email":"b@project.iam.gserviceaccount.com"}`)}
+	if got, err := adapter.credential(); err != nil || got.ClientEmail != "b@project.iam.gserviceaccount.com" {
+		t.Errorf("unexpected credential %+v, %v", got, err)
+	}
+
+	adapter = &GCSAdapter{CredentialsJSON: []byte("not json")}
+	if _, err := adapter.credential(); !errors.Is(err, ErrInvalidClient) {
+		t.Errorf("expected ErrInvalidClient, got %v", err)
+	}
+
+	adapter = &GCSAdapter{CredentialsFile: "/missing/service-account.json"}
+	if _, err := adapter.credential(); !errors.Is(err, ErrInvalidClient) {
+		t.Errorf("expected ErrInvalidClient, got %v", err)
+	}
+
+	if got, err := (&GCSAdapter{}).credential(); got != nil || err != nil {
+		t.Errorf("expected the default credentials, got %+v, %v", got, err)
+	}
+}
+
+func TestGCSEndpoint(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		want     string
+	}{
+		{"", googleGCSDomain},
+		{"http://localhost:4443", "http://localhost:4443"},
+		{"http://localhost:4443/", "http://localhost:4443"},
+		{"http://localhost:4443/storage/v1/", "http://localhost:4443"},
+	}
+	for _, tt := range tests {
+		if got := (&GCSAdapter{Endpoint: tt.endpoint}).endpoint(); got != tt.want {
+			t.Errorf("endpoint(%q) = %q, want %q", tt.endpoint, got, tt.want)
+		}
+	}
+
+	adapter := &GCSAdapter{Endpoint: "http://localhost:4443"}
+	if key, err := adapter.objectKey("bucket", "http://localhost:4443/bucket/dir/report.csv"); err != nil || key != "dir/report.csv" {
+		t.Errorf("objectKey = %q, %v", key, err)
+	}
+}
+
+func TestGCSUpdateMetadataReplacesTheMetadata(t *testing.T) {
+	var patch struct {
+		Metadata map[string]string `json:"metadata"`
+	}
+	var precondition string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/storage/v1/b/bucket/o/report.pdf" {
+			http.NotFound(w, r)
+			return
+		}
+
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPatch:
+			precondition = r.URL.Query().Get("ifMetagenerationMatch")
+			if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+				t.Error(err)
+			}
+		default:
+			t.Errorf("unexpected %s request", r.Method)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"bucket":         "bucket",
+			"name":           "report.pdf",
+			"metageneration": "3",
+			"metadata":       map[string]string{"tenant": "a", "source": "crm"},
+		})
+	}))
+	defer server.Close()
+
+	adapter := &GCSAdapter{Endpoint: server.URL}
+	err := adapter.UpdateMetadata(context.Background(), "bucket", "report.pdf", ObjectAttributes{
+		Metadata: map[string]string{"tenant": "b"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{"tenant": "b", "source": ""}
+	if !reflect.DeepEqual(patch.Metadata, want) {
+		t.Errorf("patched metadata = %v, want %v", patch.Metadata, want)
+	}
+	if precondition != "3" {
+		t.Errorf("expected the update to depend on metageneration 3, got %q", precondition)
+	}
+}
+
+func TestGCSReadAfterClose(t *testing.T) {
+	ctx := context.Background()
+	_, adapter, stop := newFakeGCS(t)
+	defer stop()
+
+	if _, err := adapter.getClient(); err != nil {
+		t.Fatal(err)
+	}
+	if err := adapter.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := adapter.openMedia(ctx, "bucket", "report.csv", 0, -1); !errors.Is(err, ErrInvalidClient) {
+		t.Errorf("expected ErrInvalidClient once closed, got %v", err)
+	}
+	// the client is created again by the next call
+	if _, err := adapter.OpenRangeReader(ctx, "bucket", "report.csv", 0, -1); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
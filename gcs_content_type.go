@@ -2,7 +2,6 @@ package storage
 
 import (
 	"fmt"
-	"strings"
 
 	"cloud.google.com/go/storage"
 )
@@ -11,6 +10,7 @@ var contentTypeMapper = map[string]func(sw *storage.Writer){
 	ContentTypeCSV:   contentTypeCSV,
 	ContentTypePNG:   contentTypePNG,
 	ContentTypeJPEG:  contentTypeJPEG,
+	ContentTypeJPG:   contentTypeJPEG,
 	ContentTypeHEIC:  contentTypeHeic,
 	ContentTypePDF:   contentTypePDF,
 	ContentTypeZip:   contentTypeZip,
@@ -19,6 +19,7 @@ var contentTypeMapper = map[string]func(sw *storage.Writer){
 	ContentTypeCSS:   contentTypeCSS,
 	ContentTypeJS:    contentTypeJS,
 	ContentTypeExcel: contentTypeExcel,
+	ContentTypeSVG:   contentTypeSVG,
 	// ContentTypeAny:  contentTypeAny,
 }
 
@@ -48,15 +49,16 @@ func contentTypePDF(sw *storage.Writer) {
 	sw.ContentType = "application/pdf"
 }
 
-func contentTypeAny(sw *storage.Writer) {
-	filenameArr := strings.Split(sw.Name, ".")
-	fileExtension := strings.TrimSpace(filenameArr[len(filenameArr)-1])
-
+// contentTypeAny : Set the content type detected from the head of the data, or
+// the one of the extension of the name when the data has no signature
+func contentTypeAny(sw *storage.Writer, head []byte) {
 	// sw.ContentDisposition = fmt.Sprintf("attachment;filename=%s", sw.Name)
 
-	contentFunc, isExist := contentTypeMapper[fileExtension]
-	if isExist {
+	key, contentType := sniffContentType(sw.Name, head)
+	if contentFunc, isExist := contentTypeMapper[key]; isExist {
 		contentFunc(sw)
+	} else if contentType != "" {
+		sw.ContentType = contentType
 	}
 }
 
@@ -78,6 +80,10 @@ func contentTypeJS(sw *storage.Writer) {
 }
 
 func contentTypeExcel(sw *storage.Writer) {
-	sw.ContentType = "application/vnd.ms-excel"
+	sw.ContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
 	sw.ContentDisposition = fmt.Sprintf("attachment;filename=%s", sw.Name)
 }
+
+func contentTypeSVG(sw *storage.Writer) {
+	sw.ContentType = "image/svg+xml"
+}
@@ -0,0 +1,155 @@
+package storage
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	gcsV4Algorithm     = "GOOG4-RSA-SHA256"
+	gcsV4MaxExpiration = 7 * 24 * time.Hour
+)
+
+// gcsSignV4 : V4 signing with the service account key, which the sdk doesn't
+// support yet, see https://cloud.google.com/storage/docs/access-control/signing-urls-manually.
+// Every header is signed, so the client must send them as they are
+func gcsSignV4(credential GoogleClient, method string, u *url.URL, header http.Header, expires time.Time, now time.Time) (string, error) {
+	now = now.UTC()
+	expiration := expires.Sub(now)
+	if expiration <= 0 || expiration > gcsV4MaxExpiration {
+		return "", fmt.Errorf("storage: gcs signed url must expire within %s", gcsV4MaxExpiration)
+	}
+
+	values := map[string]string{"host": u.Host}
+	for key := range header {
+		values[strings.ToLower(key)] = strings.TrimSpace(strings.Join(header[key], ","))
+	}
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + values[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	scope := fmt.Sprintf("%s/auto/storage/goog4_request", now.Format("20060102"))
+	query := u.Query()
+	query.Set("X-Goog-Algorithm", gcsV4Algorithm)
+	query.Set("X-Goog-Credential", credential.ClientEmail+"/"+scope)
+	query.Set("X-Goog-Date", now.Format(s3DateFormat))
+	query.Set("X-Goog-Expires", fmt.Sprintf("%d", int64(expiration/time.Second)))
+	query.Set("X-Goog-SignedHeaders", signedHeaders)
+
+	canonicalRequest := strings.Join([]string{
+		method,
+		s3EscapePath(u.Path),
+		s3CanonicalQuery(query),
+		canonicalHeaders.String(),
+		signedHeaders,
+		s3UnsignedPayload,
+	}, "\n")
+
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		gcsV4Algorithm,
+		now.Format(s3DateFormat),
+		scope,
+		hex.EncodeToString(hash[:]),
+	}, "\n")
+
+	signature, err := gcsSignString(credential, stringToSign)
+	if err != nil {
+		return "", err
+	}
+	query.Set("X-Goog-Signature", signature)
+
+	signed := *u
+	signed.RawPath = s3EscapePath(u.Path)
+	signed.RawQuery = s3CanonicalQuery(query)
+	return signed.String(), nil
+}
+
+// gcsPostPolicyV4 : Sign the policy document of a form upload, see
+// https://cloud.google.com/storage/docs/xml-api/post-object-forms
+func gcsPostPolicyV4(credential GoogleClient, endpoint, bucket, key string, options PolicyOptions, now time.Time) (*PostForm, error) {
+	now = now.UTC()
+	expiration := options.Expires.Sub(now)
+	if expiration <= 0 || expiration > gcsV4MaxExpiration {
+		return nil, fmt.Errorf("storage: gcs post policy must expire within %s", gcsV4MaxExpiration)
+	}
+
+	policy := newPostPolicy(bucket, key, options)
+	policy.set("x-goog-algorithm", gcsV4Algorithm)
+	policy.set("x-goog-credential", fmt.Sprintf("%s/%s/auto/storage/goog4_request", credential.ClientEmail, now.Format("20060102")))
+	policy.set("x-goog-date", now.Format(s3DateFormat))
+
+	document, err := policy.encode(time.RFC3339)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := gcsSignString(credential, document)
+	if err != nil {
+		return nil, err
+	}
+	policy.fields["policy"] = document
+	policy.fields["x-goog-signature"] = signature
+
+	return &PostForm{URL: endpoint + "/" + bucket + "/", Fields: policy.fields}, nil
+}
+
+// gcsSignString : RSA SHA256 with the private key of the service account, hex encoded
+func gcsSignString(credential GoogleClient, stringToSign string) (string, error) {
+	privateKey, err := parseGooglePrivateKey(credential.PrivateKey)
+	if err != nil {
+		return "", err
+	}
+
+	digest := sha256.Sum256([]byte(stringToSign))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, privateKey, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(signature), nil
+}
+
+// parseGooglePrivateKey : The private key of the service account json, PKCS#8
+// as downloaded from the console, or PKCS#1
+func parseGooglePrivateKey(privateKey string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(privateKey))
+	if block == nil {
+		return nil, fmt.Errorf("%w: invalid google private key", ErrInvalidClient)
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid google private key: %v", ErrInvalidClient, err)
+	}
+
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%w: google private key is not a rsa key", ErrInvalidClient)
+	}
+
+	return rsaKey, nil
+}
@@ -2,10 +2,11 @@ module github.com/myussufz/cloud-storage
 
 require (
 	cloud.google.com/go v0.37.2
-	github.com/aliyun/aliyun-oss-go-sdk v0.0.0-20190307165228-86c17b95fcd5
+	github.com/aliyun/aliyun-oss-go-sdk v3.0.2+incompatible
 	github.com/baiyubin/aliyun-sts-go-sdk v0.0.0-20180326062324-cfa1a18b161f // indirect
 	github.com/kr/pretty v0.1.0 // indirect
 	github.com/satori/go.uuid v1.2.0 // indirect
+	google.golang.org/api v0.3.0
 )
 
 go 1.13
@@ -0,0 +1,531 @@
+package storage
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	localScheme = "file://"
+)
+
+// LocalAdapter : Store the objects under a root directory, one sub directory per bucket
+type LocalAdapter struct {
+	Root string
+}
+
+func init() {
+	Register(LOCAL, func(config Config) (Adapter, error) {
+		var client LocalClient
+		switch config := config.(type) {
+		case LocalClient:
+			client = config
+		case *LocalClient:
+			if config == nil {
+				return nil, invalidConfig(LOCAL, config)
+			}
+			client = *config
+		default:
+			return nil, invalidConfig(LOCAL, config)
+		}
+
+		adapter := new(LocalAdapter)
+		adapter.Root = client.Root
+		return adapter, nil
+	})
+}
+
+// UploadFile : Upload file to the bucket
+func (adapter *LocalAdapter) UploadFile(file *multipart.FileHeader, bucket, filename string) (string, error) {
+	return adapter.UploadFileWithContext(context.Background(), file, bucket, filename)
+}
+
+// UploadFileWithContext : Upload file to the bucket
+func (adapter *LocalAdapter) UploadFileWithContext(ctx context.Context, file *multipart.FileHeader, bucket, filename string) (string, error) {
+	result, err := uploadMultipartFile(ctx, adapter, file, bucket, filename)
+	if err != nil {
+		return "", err
+	}
+
+	return result.URL, nil
+}
+
+// DeleteFileUsingURL : Delete file from the bucket using url
+func (adapter *LocalAdapter) DeleteFileUsingURL(bucket, fileURL string) error {
+	return adapter.DeleteFileUsingURLWithContext(context.Background(), bucket, fileURL)
+}
+
+// DeleteFileUsingURLWithContext : Delete file from the bucket using url
+func (adapter *LocalAdapter) DeleteFileUsingURLWithContext(ctx context.Context, bucket, fileURL string) error {
+	key, err := adapter.getFilePathFromURL(bucket, fileURL)
+	if err != nil {
+		return localError("delete", bucket, fileURL, err)
+	}
+
+	return adapter.DeleteObject(ctx, bucket, key)
+}
+
+// DeleteObject : Delete the object by its key
+func (adapter *LocalAdapter) DeleteObject(ctx context.Context, bucket, key string) error {
+	if err := ctx.Err(); err != nil {
+		return localError("delete", bucket, key, err)
+	}
+
+	filepath, err := adapter.getFilePath(bucket, key)
+	if err != nil {
+		return localError("delete", bucket, key, err)
+	}
+
+	// deleting a missing object succeeds, same as the providers
+	if err := os.Remove(filepath); err != nil && !os.IsNotExist(err) {
+		return localError("delete", bucket, key, err)
+	}
+
+	return nil
+}
+
+// TemporaryServingFile : The local file system has no signing, so the file url is returned as it is
+func (adapter *LocalAdapter) TemporaryServingFile(bucket, fileURL string, expiredDateTime time.Time, localClient interface{}) (string, error) {
+	return adapter.TemporaryServingFileWithContext(context.Background(), bucket, fileURL, expiredDateTime, localClient)
+}
+
+// TemporaryServingFileWithContext : The local file system has no signing, so the file url is returned as it is
+func (adapter *LocalAdapter) TemporaryServingFileWithContext(ctx context.Context, bucket, fileURL string, expiredDateTime time.Time, localClient interface{}) (string, error) {
+	key, err := adapter.getFilePathFromURL(bucket, fileURL)
+	if err != nil {
+		return "", localError("sign", bucket, fileURL, err)
+	}
+
+	return adapter.TemporaryServingObject(ctx, bucket, key, expiredDateTime, localClient)
+}
+
+// TemporaryServingObject : The local file system has no signing, so the file url is returned as it is
+func (adapter *LocalAdapter) TemporaryServingObject(ctx context.Context, bucket, key string, expiredDateTime time.Time, localClient interface{}) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", localError("sign", bucket, key, err)
+	}
+
+	filepath, err := adapter.getFilePath(bucket, key)
+	if err != nil {
+		return "", localError("sign", bucket, key, err)
+	}
+
+	if _, err := os.Stat(filepath); err != nil {
+		return "", localError("sign", bucket, key, err)
+	}
+
+	return getLocalFileURL(filepath), nil
+}
+
+// UploadReader :
+func (adapter *LocalAdapter) UploadReader(bucket, filename string, reader io.Reader, contentType string) (string, error) {
+	return adapter.UploadReaderWithContext(context.Background(), bucket, filename, reader, contentType)
+}
+
+// UploadReaderWithContext :
+func (adapter *LocalAdapter) UploadReaderWithContext(ctx context.Context, bucket, filename string, reader io.Reader, contentType string) (string, error) {
+	result, err := adapter.Put(ctx, bucket, filename, reader, contentType)
+	if err != nil {
+		return "", err
+	}
+
+	return result.URL, nil
+}
+
+// Put : Upload the reader and return the canonical identifiers of the object
+func (adapter *LocalAdapter) Put(ctx context.Context, bucket, filename string, reader io.Reader, contentType string) (*UploadResult, error) {
+	return adapter.Upload(ctx, bucket, filename, reader, UploadOptions{ContentType: contentType})
+}
+
+// Upload : The local file system keeps no attributes, so they are refused and only
+// the checksum and IfNotExists are honored. A file failing the checksum is removed,
+// same as a failed write
+func (adapter *LocalAdapter) Upload(ctx context.Context, bucket, filename string, reader io.Reader, options UploadOptions) (*UploadResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, localError("upload", bucket, filename, err)
+	}
+
+	if options.ContentType != "" {
+		if _, isExist := contentTypeMapper[options.ContentType]; !isExist {
+			return nil, localError("upload", bucket, filename, errUnsupportedContentType(options.ContentType))
+		}
+	}
+
+	if options.ContentDisposition != "" || !options.Attributes.isZero() || options.ACL != "" || options.StorageClass != "" {
+		return nil, localError("upload", bucket, filename, errUploadOptionNotSupported("local", "object attributes"))
+	}
+
+	flag := os.O_RDWR | os.O_CREATE | os.O_TRUNC
+	if options.IfNotExists {
+		flag = os.O_RDWR | os.O_CREATE | os.O_EXCL
+	}
+
+	file, err := adapter.openFile(bucket, filename, flag)
+	if os.IsExist(err) {
+		return nil, localError("upload", bucket, filename, ErrPreconditionFailed)
+	}
+	if err != nil {
+		return nil, localError("upload", bucket, filename, err)
+	}
+
+	hr := newHashReader(newContextReader(ctx, reader))
+	if _, err := io.Copy(file, hr); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		msg := fmt.Errorf("Could not write file: %w", err)
+		return nil, localError("upload", bucket, filename, msg)
+	}
+
+	if err := hr.verify(options.MD5); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, localError("upload", bucket, filename, err)
+	}
+
+	if err := file.Close(); err != nil {
+		msg := fmt.Errorf("Could not put file: %w", err)
+		return nil, localError("upload", bucket, filename, msg)
+	}
+
+	result := localUploadResult(bucket, filename, file.Name(), hr.size)
+	result.MD5 = hr.md5.Sum(nil)
+	result.ETag = hex.EncodeToString(result.MD5)
+	result.CRC32C = hr.crc32c.Sum32()
+	return result, nil
+}
+
+// Copy : Copy the file within the root. The local file system keeps no attributes,
+// so the overrides are refused
+func (adapter *LocalAdapter) Copy(ctx context.Context, src, dst ObjectRef, options CopyOptions) (*UploadResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, localError("copy", src.Bucket, src.Key, err)
+	}
+
+	if !options.isZero() {
+		return nil, localError("copy", src.Bucket, src.Key, errCopyOptionsNotSupported)
+	}
+
+	path, err := adapter.getFilePath(src.Bucket, src.Key)
+	if err != nil {
+		return nil, localError("copy", src.Bucket, src.Key, err)
+	}
+
+	source, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, localError("copy", src.Bucket, src.Key, ErrNotFound)
+	}
+	if err != nil {
+		return nil, localError("copy", src.Bucket, src.Key, err)
+	}
+	defer source.Close()
+
+	file, err := adapter.createFile(dst.Bucket, dst.Key)
+	if err != nil {
+		return nil, localError("copy", dst.Bucket, dst.Key, err)
+	}
+
+	size, err := io.Copy(file, newContextReader(ctx, source))
+	if err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, localError("copy", dst.Bucket, dst.Key, err)
+	}
+
+	if err := file.Close(); err != nil {
+		return nil, localError("copy", dst.Bucket, dst.Key, err)
+	}
+
+	return localUploadResult(dst.Bucket, dst.Key, file.Name(), size), nil
+}
+
+// move : Rename the file, which is atomic within the same file system
+func (adapter *LocalAdapter) move(ctx context.Context, src, dst ObjectRef, options CopyOptions) (*UploadResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, localError("copy", src.Bucket, src.Key, err)
+	}
+
+	if !options.isZero() {
+		return nil, localError("copy", src.Bucket, src.Key, errCopyOptionsNotSupported)
+	}
+
+	path, err := adapter.getFilePath(src.Bucket, src.Key)
+	if err != nil {
+		return nil, localError("copy", src.Bucket, src.Key, err)
+	}
+
+	fileInfo, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil, localError("copy", src.Bucket, src.Key, ErrNotFound)
+	}
+	if err != nil {
+		return nil, localError("copy", src.Bucket, src.Key, err)
+	}
+
+	dstPath, err := adapter.getFilePath(dst.Bucket, dst.Key)
+	if err != nil {
+		return nil, localError("copy", dst.Bucket, dst.Key, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dstPath), 0755); err != nil {
+		return nil, localError("copy", dst.Bucket, dst.Key, err)
+	}
+
+	if err := os.Rename(path, dstPath); err != nil {
+		return nil, localError("copy", dst.Bucket, dst.Key, err)
+	}
+
+	return localUploadResult(dst.Bucket, dst.Key, dstPath, fileInfo.Size()), nil
+}
+
+// ReadFile :
+func (adapter *LocalAdapter) ReadFile(bucket, path string) ([]byte, error) {
+	return adapter.ReadFileWithContext(context.Background(), bucket, path)
+}
+
+// ReadFileWithContext : Convenience wrapper over OpenReader, the whole object is kept in memory
+func (adapter *LocalAdapter) ReadFileWithContext(ctx context.Context, bucket, path string) ([]byte, error) {
+	rc, err := adapter.OpenRangeReader(ctx, bucket, path, 0, -1)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	return ioutil.ReadAll(rc)
+}
+
+// OpenRangeReader : Read length bytes from offset, a negative length reads until the end of the file
+func (adapter *LocalAdapter) OpenRangeReader(ctx context.Context, bucket, path string, offset, length int64) (io.ReadCloser, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, localError("read", bucket, path, err)
+	}
+
+	filepath, err := adapter.getFilePath(bucket, path)
+	if err != nil {
+		return nil, localError("read", bucket, path, err)
+	}
+
+	file, err := os.Open(filepath)
+	if os.IsNotExist(err) {
+		return nil, localError("read", bucket, path, ErrNotFound)
+	}
+	if err != nil {
+		return nil, localError("read", bucket, path, err)
+	}
+
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		file.Close()
+		return nil, localError("read", bucket, path, err)
+	}
+
+	var reader io.Reader = file
+	if length >= 0 {
+		reader = io.LimitReader(file, length)
+	}
+
+	return &readCloser{Reader: newContextReader(ctx, reader), Closer: file}, nil
+}
+
+// UploadBuffer :
+func (adapter *LocalAdapter) UploadBuffer(bucket, filename string, contentType string) (*Buffer, error) {
+	return adapter.UploadBufferWithContext(context.Background(), bucket, filename, contentType)
+}
+
+// UploadBufferWithContext :
+func (adapter *LocalAdapter) UploadBufferWithContext(ctx context.Context, bucket, filename string, contentType string) (*Buffer, error) {
+	buf := new(Buffer)
+	buf.adapter = LOCAL
+	buf.ctx = ctx
+
+	if err := ctx.Err(); err != nil {
+		return nil, localError("upload", bucket, filename, err)
+	}
+
+	if contentType != "" {
+		if _, isExist := contentTypeMapper[contentType]; !isExist {
+			return nil, localError("upload", bucket, filename, errUnsupportedContentType(contentType))
+		}
+	}
+
+	file, err := adapter.createFile(bucket, filename)
+	if err != nil {
+		return nil, localError("upload", bucket, filename, err)
+	}
+
+	buf.file = file
+	buf.filename = filename
+	buf.bucket = bucket
+	return buf, nil
+}
+
+// List : Walk the bucket directory, the content type is guessed from the extension
+func (adapter *LocalAdapter) List(ctx context.Context, bucket string, options ListOptions) (*ListResult, error) {
+	dir, err := adapter.getBucketPath(bucket)
+	if err != nil {
+		return nil, localError("list", bucket, options.Prefix, err)
+	}
+
+	objects := make([]*ObjectInfo, 0)
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		name, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		name = filepath.ToSlash(name)
+		contentType, _, _ := resolveContentType(name, "", nil)
+
+		objects = append(objects, &ObjectInfo{
+			Name:        name,
+			Size:        info.Size(),
+			ContentType: contentType,
+			Updated:     info.ModTime().UTC(),
+		})
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, localError("list", bucket, options.Prefix, err)
+	}
+
+	return listObjects(objects, options), nil
+}
+
+// Stat : The file system only knows the size and modification time, the content
+// type is guessed from the extension
+func (adapter *LocalAdapter) Stat(ctx context.Context, bucket, filename string) (*ObjectInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, localError("stat", bucket, filename, err)
+	}
+
+	path, err := adapter.getFilePath(bucket, filename)
+	if err != nil {
+		return nil, localError("stat", bucket, filename, err)
+	}
+
+	fileInfo, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil, localError("stat", bucket, filename, ErrNotFound)
+	}
+	if err != nil {
+		return nil, localError("stat", bucket, filename, err)
+	}
+	if fileInfo.IsDir() {
+		return nil, localError("stat", bucket, filename, ErrNotFound)
+	}
+
+	contentType, contentDisposition, _ := resolveContentType(filename, "", nil)
+
+	return &ObjectInfo{
+		Name:               filename,
+		Size:               fileInfo.Size(),
+		ContentType:        contentType,
+		ContentDisposition: contentDisposition,
+		Updated:            fileInfo.ModTime().UTC(),
+	}, nil
+}
+
+// Close : Nothing to release for the local file system
+func (adapter *LocalAdapter) Close() error {
+	return nil
+}
+
+func (adapter *LocalAdapter) createFile(bucket, filename string) (*os.File, error) {
+	return adapter.openFile(bucket, filename, os.O_RDWR|os.O_CREATE|os.O_TRUNC)
+}
+
+// openFile : Open the file with the flag, creating the directories of the bucket
+// and of the object name
+func (adapter *LocalAdapter) openFile(bucket, filename string, flag int) (*os.File, error) {
+	path, err := adapter.getFilePath(bucket, filename)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	return os.OpenFile(path, flag, 0666)
+}
+
+// validateBucket : Any directory name is a bucket
+func (adapter *LocalAdapter) validateBucket(bucket string) error {
+	_, err := adapter.getBucketPath(bucket)
+	return err
+}
+
+func (adapter *LocalAdapter) getBucketPath(bucket string) (string, error) {
+	if len(bucket) == 0 || strings.ContainsAny(bucket, `/\`) || bucket == "." || bucket == ".." {
+		return "", fmt.Errorf("storage: invalid bucket name %q", bucket)
+	}
+
+	return filepath.Join(adapter.Root, bucket), nil
+}
+
+// getFilePath : Resolve the object name under the bucket directory and
+// reject names escaping it, e.g. "../../etc/passwd"
+func (adapter *LocalAdapter) getFilePath(bucket, filename string) (string, error) {
+	dir, err := adapter.getBucketPath(bucket)
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, filepath.FromSlash(filename))
+	if !strings.HasPrefix(path, dir+string(filepath.Separator)) {
+		return "", fmt.Errorf("storage: invalid object name %q", filename)
+	}
+
+	return path, nil
+}
+
+func localUploadResult(bucket, filename, path string, size int64) *UploadResult {
+	contentType, _, _ := resolveContentType(filename, "", nil)
+
+	return &UploadResult{
+		Bucket:      bucket,
+		Key:         filename,
+		URL:         getLocalFileURL(path),
+		Size:        size,
+		ContentType: contentType,
+	}
+}
+
+func getLocalFileURL(path string) string {
+	path, _ = filepath.Abs(path)
+	return localScheme + escapeObjectKey(filepath.ToSlash(path))
+}
+
+func (adapter *LocalAdapter) getFilePathFromURL(bucket, fileURL string) (string, error) {
+	if !strings.HasPrefix(fileURL, localScheme) {
+		return fileURL, nil
+	}
+
+	ref, err := ParseObjectURL(fileURL)
+	if err != nil {
+		return "", err
+	}
+
+	dir, _ := filepath.Abs(filepath.Join(adapter.Root, bucket))
+	prefix := filepath.ToSlash(dir) + "/"
+	if !strings.HasPrefix(ref.Key, prefix) {
+		return "", fmt.Errorf("storage: object url %q is not in bucket %q", fileURL, bucket)
+	}
+
+	return strings.TrimPrefix(ref.Key, prefix), nil
+}
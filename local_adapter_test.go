@@ -0,0 +1,172 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newLocalTestAdapter(t *testing.T) (*LocalAdapter, func()) {
+	root, err := ioutil.TempDir("", "storage")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &LocalAdapter{Root: root}, func() { os.RemoveAll(root) }
+}
+
+func TestLocalGetFilePath(t *testing.T) {
+	adapter := &LocalAdapter{Root: "/data"}
+
+	tests := []struct {
+		bucket, name string
+		want         string
+	}{
+		{"bucket", "report.csv", "/data/bucket/report.csv"},
+		{"bucket", "dir/report.csv", "/data/bucket/dir/report.csv"},
+		{"bucket", "dir/../report.csv", "/data/bucket/report.csv"},
+		{"bucket", "../other/report.csv", ""},
+		{"bucket", "../../etc/passwd", ""},
+		{"bucket", "..", ""},
+		{"bucket", "", ""},
+		{"..", "report.csv", ""},
+		{"a/b", "report.csv", ""},
+		{"", "report.csv", ""},
+	}
+
+	for _, tt := range tests {
+		got, err := adapter.getFilePath(tt.bucket, tt.name)
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("getFilePath(%q, %q) = %q, expected an error", tt.bucket, tt.name, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("getFilePath(%q, %q): %v", tt.bucket, tt.name, err)
+			continue
+		}
+		if got != filepath.FromSlash(tt.want) {
+			t.Errorf("getFilePath(%q, %q) = %q, want %q", tt.bucket, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLocalUploadReadDelete(t *testing.T) {
+	adapter, cleanup := newLocalTestAdapter(t)
+	defer cleanup()
+
+	fileURL, err := adapter.UploadReader("bucket", "dir/report.csv", strings.NewReader("a,b\n"), ContentTypeCSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(fileURL, localScheme) || !strings.HasSuffix(fileURL, "/bucket/dir/report.csv") {
+		t.Errorf("unexpected url %q", fileURL)
+	}
+
+	data, err := adapter.ReadFile("bucket", "dir/report.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "a,b\n" {
+		t.Errorf("read %q", data)
+	}
+
+	if err := adapter.DeleteFileUsingURL("bucket", fileURL); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := adapter.ReadFile("bucket", "dir/report.csv"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected the file to be deleted, got %v", err)
+	}
+}
+
+func TestLocalUploadRejectsTraversal(t *testing.T) {
+	adapter, cleanup := newLocalTestAdapter(t)
+	defer cleanup()
+
+	if _, err := adapter.UploadReader("bucket", "../escaped.csv", strings.NewReader("a,b"), ""); err == nil {
+		t.Fatal("expected a name escaping the bucket to be refused")
+	}
+	if _, err := os.Stat(filepath.Join(adapter.Root, "escaped.csv")); !os.IsNotExist(err) {
+		t.Errorf("a file is written outside of the bucket: %v", err)
+	}
+
+	if _, err := adapter.ReadFile("bucket", "../../etc/passwd"); err == nil {
+		t.Error("expected a read escaping the bucket to be refused")
+	}
+	if err := adapter.DeleteFileUsingURL("bucket", "../other/report.csv"); err == nil {
+		t.Error("expected a delete escaping the bucket to be refused")
+	}
+}
+
+func TestLocalUploadUnsupportedContentType(t *testing.T) {
+	adapter, cleanup := newLocalTestAdapter(t)
+	defer cleanup()
+
+	if _, err := adapter.UploadReader("bucket", "report.doc", strings.NewReader("data"), "msword"); err == nil {
+		t.Error("expected an unsupported content type to be refused")
+	}
+}
+
+func TestLocalUploadBuffer(t *testing.T) {
+	adapter, cleanup := newLocalTestAdapter(t)
+	defer cleanup()
+
+	buf, err := adapter.UploadBuffer("bucket", "log.csv", ContentTypeCSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"a,b\n", "1,2\n", "3,4\n"} {
+		if err := buf.CopyString(line); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := buf.CopyByte([]byte("5,6\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	fileURL, err := buf.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(fileURL, "/bucket/log.csv") {
+		t.Errorf("unexpected url %q", fileURL)
+	}
+
+	data, err := adapter.ReadFile("bucket", "log.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "a,b\n1,2\n3,4\n5,6\n" {
+		t.Errorf("read %q", data)
+	}
+}
+
+func TestLocalUploadRefusesAttributes(t *testing.T) {
+	adapter, cleanup := newLocalTestAdapter(t)
+	defer cleanup()
+
+	builder := New(LocalClient{Root: adapter.Root})
+	for _, option := range []UploadOption{
+		WithMetadata(map[string]string{"tenant": "a"}),
+		WithCacheControl("no-cache"),
+		WithContentDisposition("attachment"),
+		WithACL(ACLPrivate),
+		WithStorageClass("COLD"),
+	} {
+		_, err := builder.Upload(context.Background(), "bucket", "report.csv", strings.NewReader("a,b"), WithContentType(ContentTypeCSV), option)
+		if !errors.Is(err, ErrUnsupported) {
+			t.Errorf("expected ErrUnsupported, got %v", err)
+		}
+	}
+	if _, err := builder.Stat("bucket", "report.csv"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected nothing to be written, got %v", err)
+	}
+
+	if _, err := builder.Upload(context.Background(), "bucket", "report.csv", strings.NewReader("a,b"), WithContentType(ContentTypeCSV)); err != nil {
+		t.Errorf("expected the upload without attributes, got %v", err)
+	}
+}
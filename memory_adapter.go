@@ -0,0 +1,569 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	memoryScheme = "mem://"
+)
+
+// MemoryObject : The object kept by MemoryAdapter
+type MemoryObject struct {
+	Bucket             string
+	Name               string
+	Data               []byte
+	ContentType        string
+	ContentDisposition string
+	ContentEncoding    string
+	ContentLanguage    string
+	CacheControl       string
+	ACL                string
+	StorageClass       string
+	Metadata           map[string]string
+	Tags               map[string]string
+	Created            time.Time
+	Updated            time.Time
+}
+
+// MemoryAdapter : Keep the objects in a map, mostly for unit tests
+type MemoryAdapter struct {
+	mu      sync.RWMutex
+	objects map[memoryKey]*MemoryObject
+}
+
+// memoryKey : The bucket and name apart, so a slash in either can't make two
+// objects collide
+type memoryKey struct {
+	bucket string
+	name   string
+}
+
+// NewMemoryAdapter :
+func NewMemoryAdapter() *MemoryAdapter {
+	return &MemoryAdapter{objects: make(map[memoryKey]*MemoryObject)}
+}
+
+func init() {
+	Register(MEMORY, func(config Config) (Adapter, error) {
+		switch config := config.(type) {
+		case *MemoryAdapter:
+			if config != nil {
+				return config, nil
+			}
+		case nil, MemoryClient, *MemoryClient:
+			return NewMemoryAdapter(), nil
+		}
+		return nil, invalidConfig(MEMORY, config)
+	})
+}
+
+// Driver : The adapter is its own config, so New shares it with the caller
+func (adapter *MemoryAdapter) Driver() string {
+	return MEMORY
+}
+
+// Object : Return a copy of the object, so the tests can inspect what got written
+func (adapter *MemoryAdapter) Object(bucket, name string) (MemoryObject, bool) {
+	adapter.mu.RLock()
+	defer adapter.mu.RUnlock()
+
+	object, isExist := adapter.objects[memoryKey{bucket, name}]
+	if !isExist {
+		return MemoryObject{}, false
+	}
+
+	return object.copy(), true
+}
+
+// Objects : Return a copy of every object in the bucket
+func (adapter *MemoryAdapter) Objects(bucket string) []MemoryObject {
+	adapter.mu.RLock()
+	defer adapter.mu.RUnlock()
+
+	objects := make([]MemoryObject, 0)
+	for _, object := range adapter.objects {
+		if object.Bucket == bucket {
+			objects = append(objects, object.copy())
+		}
+	}
+
+	return objects
+}
+
+// UploadFile : Upload file to the bucket
+func (adapter *MemoryAdapter) UploadFile(file *multipart.FileHeader, bucket, filename string) (string, error) {
+	return adapter.UploadFileWithContext(context.Background(), file, bucket, filename)
+}
+
+// UploadFileWithContext : Upload file to the bucket
+func (adapter *MemoryAdapter) UploadFileWithContext(ctx context.Context, file *multipart.FileHeader, bucket, filename string) (string, error) {
+	result, err := uploadMultipartFile(ctx, adapter, file, bucket, filename)
+	if err != nil {
+		return "", err
+	}
+
+	return result.URL, nil
+}
+
+// DeleteFileUsingURL : Delete file from the bucket using url
+func (adapter *MemoryAdapter) DeleteFileUsingURL(bucket, fileURL string) error {
+	return adapter.DeleteFileUsingURLWithContext(context.Background(), bucket, fileURL)
+}
+
+// DeleteFileUsingURLWithContext : Delete file from the bucket using url
+func (adapter *MemoryAdapter) DeleteFileUsingURLWithContext(ctx context.Context, bucket, fileURL string) error {
+	key, err := objectKeyFromURL(bucket, fileURL)
+	if err != nil {
+		return memoryError("delete", bucket, fileURL, err)
+	}
+
+	return adapter.DeleteObject(ctx, bucket, key)
+}
+
+// DeleteObject : Delete the object by its key
+func (adapter *MemoryAdapter) DeleteObject(ctx context.Context, bucket, key string) error {
+	if err := ctx.Err(); err != nil {
+		return memoryError("delete", bucket, key, err)
+	}
+
+	// deleting a missing object succeeds, same as s3 and azure
+	adapter.mu.Lock()
+	defer adapter.mu.Unlock()
+
+	delete(adapter.objects, memoryKey{bucket, key})
+
+	return nil
+}
+
+// TemporaryServingFile : The memory adapter has no signing, so the file url is returned as it is
+func (adapter *MemoryAdapter) TemporaryServingFile(bucket, fileURL string, expiredDateTime time.Time, memoryClient interface{}) (string, error) {
+	return adapter.TemporaryServingFileWithContext(context.Background(), bucket, fileURL, expiredDateTime, memoryClient)
+}
+
+// TemporaryServingFileWithContext : The memory adapter has no signing, so the file url is returned as it is
+func (adapter *MemoryAdapter) TemporaryServingFileWithContext(ctx context.Context, bucket, fileURL string, expiredDateTime time.Time, memoryClient interface{}) (string, error) {
+	key, err := objectKeyFromURL(bucket, fileURL)
+	if err != nil {
+		return "", memoryError("sign", bucket, fileURL, err)
+	}
+
+	return adapter.TemporaryServingObject(ctx, bucket, key, expiredDateTime, memoryClient)
+}
+
+// TemporaryServingObject : The memory adapter has no signing, so the file url is returned as it is
+func (adapter *MemoryAdapter) TemporaryServingObject(ctx context.Context, bucket, key string, expiredDateTime time.Time, memoryClient interface{}) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", memoryError("sign", bucket, key, err)
+	}
+
+	if _, isExist := adapter.Object(bucket, key); !isExist {
+		return "", memoryError("sign", bucket, key, ErrNotFound)
+	}
+
+	return getMemoryFileURL(bucket, key), nil
+}
+
+// UploadReader :
+func (adapter *MemoryAdapter) UploadReader(bucket, filename string, reader io.Reader, contentType string) (string, error) {
+	return adapter.UploadReaderWithContext(context.Background(), bucket, filename, reader, contentType)
+}
+
+// UploadReaderWithContext :
+func (adapter *MemoryAdapter) UploadReaderWithContext(ctx context.Context, bucket, filename string, reader io.Reader, contentType string) (string, error) {
+	result, err := adapter.Put(ctx, bucket, filename, reader, contentType)
+	if err != nil {
+		return "", err
+	}
+
+	return result.URL, nil
+}
+
+// Put : Upload the reader and return the canonical identifiers of the object
+func (adapter *MemoryAdapter) Put(ctx context.Context, bucket, filename string, reader io.Reader, contentType string) (*UploadResult, error) {
+	return adapter.PutWithAttributes(ctx, bucket, filename, reader, contentType, ObjectAttributes{})
+}
+
+// PutWithAttributes : Same as Put, with the attributes kept on the object
+func (adapter *MemoryAdapter) PutWithAttributes(ctx context.Context, bucket, filename string, reader io.Reader, contentType string, attrs ObjectAttributes) (*UploadResult, error) {
+	return adapter.Upload(ctx, bucket, filename, reader, UploadOptions{ContentType: contentType, Attributes: attrs})
+}
+
+// Upload : Every option is kept on the object, so the tests can check them
+func (adapter *MemoryAdapter) Upload(ctx context.Context, bucket, filename string, reader io.Reader, options UploadOptions) (*UploadResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, memoryError("upload", bucket, filename, err)
+	}
+
+	if err := adapter.validateBucket(bucket); err != nil {
+		return nil, memoryError("upload", bucket, filename, err)
+	}
+
+	if options.ContentType != "" {
+		if _, isExist := contentTypeMapper[options.ContentType]; !isExist {
+			return nil, memoryError("upload", bucket, filename, errUnsupportedContentType(options.ContentType))
+		}
+	}
+
+	hr := newHashReader(newContextReader(ctx, reader))
+	data, err := ioutil.ReadAll(hr)
+	if err != nil {
+		msg := fmt.Errorf("Could not write file: %w", err)
+		return nil, memoryError("upload", bucket, filename, msg)
+	}
+
+	// the whole data is read already, so it is sniffed instead of a peeked head
+	ct, disposition, err := resolveContentType(filename, options.ContentType, data)
+	if err != nil {
+		return nil, memoryError("upload", bucket, filename, err)
+	}
+	if options.ContentDisposition != "" {
+		disposition = options.ContentDisposition
+	}
+
+	if err := hr.verify(options.MD5); err != nil {
+		return nil, memoryError("upload", bucket, filename, err)
+	}
+
+	object := newMemoryObject(bucket, filename, data, ct, disposition)
+	object.CacheControl = options.Attributes.CacheControl
+	object.ContentEncoding = options.Attributes.ContentEncoding
+	object.ContentLanguage = options.Attributes.ContentLanguage
+	if options.Attributes.Metadata != nil {
+		object.Metadata = copyMap(options.Attributes.Metadata)
+	}
+	object.Tags = copyMap(options.Attributes.Tags)
+	object.ACL = options.ACL
+	object.StorageClass = options.StorageClass
+
+	if err := adapter.store(object, options.IfNotExists); err != nil {
+		return nil, memoryError("upload", bucket, filename, err)
+	}
+
+	return adapter.uploadResult(bucket, filename)
+}
+
+// UpdateMetadata : Change the attributes kept on the object
+func (adapter *MemoryAdapter) UpdateMetadata(ctx context.Context, bucket, key string, attrs ObjectAttributes) error {
+	if err := ctx.Err(); err != nil {
+		return memoryError("update", bucket, key, err)
+	}
+
+	return memoryError("update", bucket, key, adapter.update(bucket, key, attrs))
+}
+
+// Copy : Copy the object, with the overrides of the options
+func (adapter *MemoryAdapter) Copy(ctx context.Context, src, dst ObjectRef, options CopyOptions) (*UploadResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, memoryError("copy", src.Bucket, src.Key, err)
+	}
+
+	adapter.mu.Lock()
+	source, isExist := adapter.objects[memoryKey{src.Bucket, src.Key}]
+	if !isExist {
+		adapter.mu.Unlock()
+		return nil, memoryError("copy", src.Bucket, src.Key, ErrNotFound)
+	}
+
+	object := source.copy()
+	object.Bucket = dst.Bucket
+	object.Name = dst.Key
+	if options.ContentType != "" {
+		object.ContentType = options.ContentType
+	}
+	if options.Metadata != nil {
+		object.Metadata = copyMap(options.Metadata)
+	}
+	object.Created = time.Now().UTC()
+	object.Updated = object.Created
+	adapter.objects[memoryKey{dst.Bucket, dst.Key}] = &object
+	adapter.mu.Unlock()
+
+	return adapter.uploadResult(dst.Bucket, dst.Key)
+}
+
+// ReadFile :
+func (adapter *MemoryAdapter) ReadFile(bucket, path string) ([]byte, error) {
+	return adapter.ReadFileWithContext(context.Background(), bucket, path)
+}
+
+// ReadFileWithContext : Convenience wrapper over OpenReader, the whole object is kept in memory
+func (adapter *MemoryAdapter) ReadFileWithContext(ctx context.Context, bucket, path string) ([]byte, error) {
+	rc, err := adapter.OpenRangeReader(ctx, bucket, path, 0, -1)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	return ioutil.ReadAll(rc)
+}
+
+// OpenRangeReader : Read length bytes from offset, a negative length reads until the end of the object
+func (adapter *MemoryAdapter) OpenRangeReader(ctx context.Context, bucket, path string, offset, length int64) (io.ReadCloser, error) {
+	var errInvalidOffset = errors.New("storage: offset must not be negative")
+
+	if err := ctx.Err(); err != nil {
+		return nil, memoryError("read", bucket, path, err)
+	}
+
+	if offset < 0 {
+		return nil, memoryError("read", bucket, path, errInvalidOffset)
+	}
+
+	object, isExist := adapter.Object(bucket, path)
+	if !isExist {
+		return nil, memoryError("read", bucket, path, ErrNotFound)
+	}
+
+	data := object.Data
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	data = data[offset:]
+	if length >= 0 && length < int64(len(data)) {
+		data = data[:length]
+	}
+
+	return ioutil.NopCloser(bytes.NewReader(data)), nil
+}
+
+// UploadBuffer :
+func (adapter *MemoryAdapter) UploadBuffer(bucket, filename string, contentType string) (*Buffer, error) {
+	return adapter.UploadBufferWithContext(context.Background(), bucket, filename, contentType)
+}
+
+// UploadBufferWithContext :
+func (adapter *MemoryAdapter) UploadBufferWithContext(ctx context.Context, bucket, filename string, contentType string) (*Buffer, error) {
+	buf := new(Buffer)
+	buf.adapter = MEMORY
+	buf.ctx = ctx
+
+	if err := ctx.Err(); err != nil {
+		return nil, memoryError("upload", bucket, filename, err)
+	}
+
+	if err := adapter.validateBucket(bucket); err != nil {
+		return nil, memoryError("upload", bucket, filename, err)
+	}
+
+	ct, disposition, err := resolveContentType(filename, contentType, nil)
+	if err != nil {
+		return nil, memoryError("upload", bucket, filename, err)
+	}
+
+	// the object is visible while it is being written, same as aliyun append
+	adapter.put(bucket, filename, nil, ct, disposition)
+
+	buf.memory = adapter
+	buf.filename = filename
+	buf.bucket = bucket
+	return buf, nil
+}
+
+// List :
+func (adapter *MemoryAdapter) List(ctx context.Context, bucket string, options ListOptions) (*ListResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, memoryError("list", bucket, options.Prefix, err)
+	}
+
+	adapter.mu.RLock()
+	objects := make([]*ObjectInfo, 0)
+	for _, object := range adapter.objects {
+		if object.Bucket != bucket {
+			continue
+		}
+		objects = append(objects, &ObjectInfo{
+			Name:        object.Name,
+			Size:        int64(len(object.Data)),
+			ContentType: object.ContentType,
+			Updated:     object.Updated,
+		})
+	}
+	adapter.mu.RUnlock()
+
+	return listObjects(objects, options), nil
+}
+
+// Stat : The md5, etag and crc32c are computed from the data, same as gcs
+func (adapter *MemoryAdapter) Stat(ctx context.Context, bucket, filename string) (*ObjectInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, memoryError("stat", bucket, filename, err)
+	}
+
+	object, isExist := adapter.Object(bucket, filename)
+	if !isExist {
+		return nil, memoryError("stat", bucket, filename, ErrNotFound)
+	}
+
+	sum := md5.Sum(object.Data)
+
+	return &ObjectInfo{
+		Name:               object.Name,
+		Size:               int64(len(object.Data)),
+		ContentType:        object.ContentType,
+		ContentDisposition: object.ContentDisposition,
+		ContentEncoding:    object.ContentEncoding,
+		ContentLanguage:    object.ContentLanguage,
+		CacheControl:       object.CacheControl,
+		ETag:               hex.EncodeToString(sum[:]),
+		MD5:                sum[:],
+		CRC32C:             crc32.Checksum(object.Data, crc32.MakeTable(crc32.Castagnoli)),
+		Created:            object.Created,
+		Updated:            object.Updated,
+		Metadata:           object.Metadata,
+	}, nil
+}
+
+// Close : The objects are kept, so the tests can still inspect them afterwards
+func (adapter *MemoryAdapter) Close() error {
+	return nil
+}
+
+func (adapter *MemoryAdapter) uploadResult(bucket, filename string) (*UploadResult, error) {
+	info, err := adapter.Stat(context.Background(), bucket, filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return &UploadResult{
+		Bucket:      bucket,
+		Key:         filename,
+		URL:         getMemoryFileURL(bucket, filename),
+		Size:        info.Size,
+		ContentType: info.ContentType,
+		ETag:        info.ETag,
+		MD5:         info.MD5,
+		CRC32C:      info.CRC32C,
+	}, nil
+}
+
+func (adapter *MemoryAdapter) put(bucket, filename string, data []byte, contentType, contentDisposition string) {
+	adapter.store(newMemoryObject(bucket, filename, data, contentType, contentDisposition), false)
+}
+
+// store : Keep the object, an existing one is only replaced when ifNotExists is false
+func (adapter *MemoryAdapter) store(object *MemoryObject, ifNotExists bool) error {
+	adapter.mu.Lock()
+	defer adapter.mu.Unlock()
+
+	if adapter.objects == nil {
+		adapter.objects = make(map[memoryKey]*MemoryObject)
+	}
+
+	key := memoryKey{object.Bucket, object.Name}
+	if _, isExist := adapter.objects[key]; isExist && ifNotExists {
+		return ErrPreconditionFailed
+	}
+	adapter.objects[key] = object
+
+	return nil
+}
+
+func newMemoryObject(bucket, filename string, data []byte, contentType, contentDisposition string) *MemoryObject {
+	now := time.Now().UTC()
+	return &MemoryObject{
+		Bucket:             bucket,
+		Name:               filename,
+		Data:               data,
+		ContentType:        contentType,
+		ContentDisposition: contentDisposition,
+		Metadata:           make(map[string]string),
+		Created:            now,
+		Updated:            now,
+	}
+}
+
+// update : Apply the attributes which are set, a non nil map replaces the current one
+func (adapter *MemoryAdapter) update(bucket, filename string, attrs ObjectAttributes) error {
+	adapter.mu.Lock()
+	defer adapter.mu.Unlock()
+
+	object, isExist := adapter.objects[memoryKey{bucket, filename}]
+	if !isExist {
+		return ErrNotFound
+	}
+
+	if attrs.CacheControl != "" {
+		object.CacheControl = attrs.CacheControl
+	}
+	if attrs.ContentEncoding != "" {
+		object.ContentEncoding = attrs.ContentEncoding
+	}
+	if attrs.ContentLanguage != "" {
+		object.ContentLanguage = attrs.ContentLanguage
+	}
+	if attrs.Metadata != nil {
+		object.Metadata = copyMap(attrs.Metadata)
+	}
+	if attrs.Tags != nil {
+		object.Tags = copyMap(attrs.Tags)
+	}
+	object.Updated = time.Now().UTC()
+
+	return nil
+}
+
+func (adapter *MemoryAdapter) append(bucket, filename string, reader io.Reader) error {
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+
+	adapter.mu.Lock()
+	defer adapter.mu.Unlock()
+
+	object, isExist := adapter.objects[memoryKey{bucket, filename}]
+	if !isExist {
+		return ErrNotFound
+	}
+	object.Data = append(object.Data, data...)
+	object.Updated = time.Now().UTC()
+
+	return nil
+}
+
+func (object *MemoryObject) copy() MemoryObject {
+	o := *object
+	o.Data = append([]byte(nil), object.Data...)
+	o.Metadata = copyMap(object.Metadata)
+	o.Tags = copyMap(object.Tags)
+
+	return o
+}
+
+func copyMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+
+	c := make(map[string]string, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+// validateBucket : The bucket is the first segment of the mem:// urls, so it has no slash
+func (adapter *MemoryAdapter) validateBucket(bucket string) error {
+	if len(bucket) == 0 || strings.Contains(bucket, "/") {
+		return errInvalidBucketName(bucket)
+	}
+
+	return nil
+}
+
+func getMemoryFileURL(bucket, filename string) string {
+	return fmt.Sprintf("%s%s/%s", memoryScheme, bucket, escapeObjectKey(filename))
+}
@@ -0,0 +1,141 @@
+package storage
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestMemoryUploadAndRead(t *testing.T) {
+	adapter := NewMemoryAdapter()
+
+	fileURL, err := adapter.UploadReader("bucket", "dir/report.csv", strings.NewReader("a,b\n"), ContentTypeCSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fileURL != "mem://bucket/dir/report.csv" {
+		t.Errorf("unexpected url %q", fileURL)
+	}
+
+	data, err := adapter.ReadFile("bucket", "dir/report.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "a,b\n" {
+		t.Errorf("read %q", data)
+	}
+
+	object, isExist := adapter.Object("bucket", "dir/report.csv")
+	if !isExist {
+		t.Fatal("the object is not stored")
+	}
+	if object.ContentType != "text/csv" {
+		t.Errorf("content type = %q, want text/csv", object.ContentType)
+	}
+
+	if _, err := adapter.ReadFile("other", "dir/report.csv"); err == nil {
+		t.Error("expected the object to be missing from another bucket")
+	}
+	if _, err := adapter.UploadReader("bucket", "report.doc", strings.NewReader("data"), "msword"); err == nil {
+		t.Error("expected an unsupported content type to be refused")
+	}
+}
+
+func TestMemoryObjectIsACopy(t *testing.T) {
+	adapter := NewMemoryAdapter()
+	if _, err := adapter.UploadReader("bucket", "data", strings.NewReader("abc"), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	object, _ := adapter.Object("bucket", "data")
+	object.Data[0] = 'x'
+	object.Metadata["tenant"] = "a"
+
+	stored, _ := adapter.Object("bucket", "data")
+	if string(stored.Data) != "abc" || len(stored.Metadata) != 0 {
+		t.Errorf("the stored object is changed through its copy: %+v", stored)
+	}
+}
+
+func TestMemoryObjects(t *testing.T) {
+	adapter := NewMemoryAdapter()
+	for _, name := range []string{"a", "b", "c"} {
+		if _, err := adapter.UploadReader("bucket", name, strings.NewReader(name), ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := adapter.UploadReader("other", "d", strings.NewReader("d"), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if objects := adapter.Objects("bucket"); len(objects) != 3 {
+		t.Errorf("expected 3 objects, got %d", len(objects))
+	}
+}
+
+func TestMemoryDeleteFileUsingURL(t *testing.T) {
+	adapter := NewMemoryAdapter()
+	fileURL, err := adapter.UploadReader("bucket", "report.csv", strings.NewReader("a,b"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := adapter.DeleteFileUsingURL("bucket", fileURL); err != nil {
+		t.Fatal(err)
+	}
+	if _, isExist := adapter.Object("bucket", "report.csv"); isExist {
+		t.Error("the object is not deleted")
+	}
+	// deleting a missing object succeeds, same as s3 and azure
+	if err := adapter.DeleteFileUsingURL("bucket", fileURL); err != nil {
+		t.Errorf("expected deleting a missing object to succeed, got %v", err)
+	}
+}
+
+func TestMemoryUploadBuffer(t *testing.T) {
+	adapter := NewMemoryAdapter()
+
+	buf, err := adapter.UploadBuffer("bucket", "log.csv", ContentTypeCSV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := buf.CopyString("a,b\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the object is visible while it is being written
+	if object, _ := adapter.Object("bucket", "log.csv"); string(object.Data) != "a,b\n" {
+		t.Errorf("read %q while writing", object.Data)
+	}
+
+	if err := buf.CopyByte([]byte("1,2\n")); err != nil {
+		t.Fatal(err)
+	}
+	fileURL, err := buf.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fileURL != "mem://bucket/log.csv" {
+		t.Errorf("unexpected url %q", fileURL)
+	}
+
+	data, _ := adapter.ReadFile("bucket", "log.csv")
+	if string(data) != "a,b\n1,2\n" {
+		t.Errorf("read %q", data)
+	}
+}
+
+func TestMemoryKeysDontCollide(t *testing.T) {
+	adapter := NewMemoryAdapter()
+	ctx := context.Background()
+
+	if _, err := adapter.Put(ctx, "a", "b/report.csv", strings.NewReader("a,b"), ContentTypeCSV); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := adapter.Put(ctx, "a/b", "report.csv", strings.NewReader("c,d"), ContentTypeCSV); err == nil {
+		t.Error("expected a bucket with a slash to be refused")
+	}
+	if _, isExist := adapter.Object("a/b", "report.csv"); isExist {
+		t.Error("expected the object to be kept apart from the bucket a")
+	}
+}
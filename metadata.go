@@ -0,0 +1,182 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// ObjectAttributes : The attributes set on upload, or changed with UpdateMetadata.
+// Metadata are the custom x-goog-meta-*, x-oss-meta-*, x-amz-meta-* or x-ms-meta-*
+// pairs. Tags are supported by s3, azure and memory, aliyun sets them on upload only
+// and gcs can keep the same pairs as Metadata. On update, the empty fields are left
+// as they are and a non nil map replaces the current one
+type ObjectAttributes struct {
+	Metadata        map[string]string
+	CacheControl    string
+	ContentEncoding string
+	ContentLanguage string
+	Tags            map[string]string
+}
+
+// AttributesPutter : Implemented by the adapters which set attributes on upload
+type AttributesPutter interface {
+	PutWithAttributes(ctx context.Context, bucket, name string, reader io.Reader, contentType string, attrs ObjectAttributes) (*UploadResult, error)
+}
+
+// MetadataUpdater : Implemented by the adapters which change the attributes of an
+// existing object
+type MetadataUpdater interface {
+	UpdateMetadata(ctx context.Context, bucket, key string, attrs ObjectAttributes) error
+}
+
+var _ AttributesPutter = &GCSAdapter{}
+var _ AttributesPutter = &AliyunAdapter{}
+var _ AttributesPutter = &MemoryAdapter{}
+var _ AttributesPutter = &S3Adapter{}
+var _ AttributesPutter = &AzureBlobAdapter{}
+
+var _ MetadataUpdater = &GCSAdapter{}
+var _ MetadataUpdater = &AliyunAdapter{}
+var _ MetadataUpdater = &MemoryAdapter{}
+var _ MetadataUpdater = &S3Adapter{}
+var _ MetadataUpdater = &AzureBlobAdapter{}
+
+// PutWithAttributes : Same as Put, with the metadata, content headers and tags of attrs
+func (b *Builder) PutWithAttributes(ctx context.Context, bucket, name string, reader io.Reader, contentType string, attrs ObjectAttributes) (*UploadResult, error) {
+	return b.Upload(ctx, bucket, name, reader, WithContentType(contentType), WithAttributes(attrs))
+}
+
+// UpdateMetadata : Change the attributes of an existing object without uploading it again
+func (b *Builder) UpdateMetadata(bucket, key string, attrs ObjectAttributes) error {
+	return b.UpdateMetadataWithContext(context.Background(), bucket, key, attrs)
+}
+
+// UpdateMetadataWithContext :
+func (b *Builder) UpdateMetadataWithContext(ctx context.Context, bucket, key string, attrs ObjectAttributes) error {
+	if b.err != nil {
+		return b.err
+	}
+	var (
+		errKeyIsRequired    = errors.New("storage: key is required")
+		errBucketIsRequired = errors.New("storage: bucket is required")
+	)
+
+	if len(key) == 0 {
+		return errKeyIsRequired
+	}
+
+	if len(bucket) == 0 {
+		return errBucketIsRequired
+	}
+
+	if attrs.isZero() {
+		return nil
+	}
+
+	updater, ok := b.adapter.(MetadataUpdater)
+	if !ok {
+		return fmt.Errorf("%w: %T can't update object attributes", ErrUnsupported, b.adapter)
+	}
+
+	return updater.UpdateMetadata(ctx, bucket, key, attrs)
+}
+
+func (attrs ObjectAttributes) isZero() bool {
+	return attrs.Metadata == nil && attrs.Tags == nil &&
+		attrs.CacheControl == "" && attrs.ContentEncoding == "" && attrs.ContentLanguage == ""
+}
+
+// hasHeaders : Anything but the tags is set, which are stored apart on every provider
+func (attrs ObjectAttributes) hasHeaders() bool {
+	attrs.Tags = nil
+	return !attrs.isZero()
+}
+
+// header : The attributes as the request headers of aliyun and s3, but the tags
+func (attrs ObjectAttributes) header(metaPrefix string) http.Header {
+	header := make(http.Header)
+	if attrs.CacheControl != "" {
+		header.Set("Cache-Control", attrs.CacheControl)
+	}
+	if attrs.ContentEncoding != "" {
+		header.Set("Content-Encoding", attrs.ContentEncoding)
+	}
+	if attrs.ContentLanguage != "" {
+		header.Set("Content-Language", attrs.ContentLanguage)
+	}
+	for key, value := range attrs.Metadata {
+		header.Set(metaPrefix+key, value)
+	}
+
+	return header
+}
+
+// replaceHeaders : The content headers and metadata of the source with the changes
+// applied, for the providers which replace all of them as soon as one is changed
+func replaceHeaders(source http.Header, metaPrefix, contentType string, attrs ObjectAttributes) http.Header {
+	header := make(http.Header)
+	for _, key := range []string{"Content-Type", "Content-Disposition", "Content-Encoding", "Content-Language", "Cache-Control"} {
+		if value := source.Get(key); value != "" {
+			header.Set(key, value)
+		}
+	}
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+
+	if attrs.Metadata == nil {
+		for key := range source {
+			if strings.HasPrefix(strings.ToLower(key), strings.ToLower(metaPrefix)) {
+				header[key] = source[key]
+			}
+		}
+	}
+	for key, values := range attrs.header(metaPrefix) {
+		header[key] = values
+	}
+
+	return header
+}
+
+// tagQuery : The tags as the url encoded query of the x-amz-tagging and x-ms-tags headers
+func tagQuery(tags map[string]string) string {
+	query := make(url.Values)
+	for key, value := range tags {
+		query.Set(key, value)
+	}
+	return query.Encode()
+}
+
+// tagSet : The tags as the TagSet element of s3 and azure, in key order
+type tagSet struct {
+	Tags []tag `xml:"TagSet>Tag"`
+}
+
+type tag struct {
+	Key   string
+	Value string
+}
+
+func newTagSet(tags map[string]string) tagSet {
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	set := tagSet{Tags: make([]tag, 0, len(keys))}
+	for _, key := range keys {
+		set.Tags = append(set.Tags, tag{Key: key, Value: tags[key]})
+	}
+	return set
+}
+
+func errTagsNotSupported(provider string) error {
+	return fmt.Errorf("%w: %s has no object tags, keep them as metadata", ErrUnsupported, provider)
+}
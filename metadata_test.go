@@ -0,0 +1,145 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestMemoryAttributes(t *testing.T) {
+	ctx := context.Background()
+	adapter := NewMemoryAdapter()
+	builder := New(adapter)
+
+	_, err := builder.PutWithAttributes(ctx, "bucket", "report.csv", strings.NewReader("a,b"), ContentTypeCSV, ObjectAttributes{
+		Metadata:     map[string]string{"tenant": "a"},
+		CacheControl: "no-cache",
+		Tags:         map[string]string{"env": "prod"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := builder.StatWithContext(ctx, "bucket", "report.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.CacheControl != "no-cache" || info.Metadata["tenant"] != "a" {
+		t.Errorf("unexpected attributes %+v", info)
+	}
+
+	// the empty fields are left as they are, a non nil map replaces the current one
+	if err := builder.Bucket("bucket").UpdateMetadata(ctx, "report.csv", ObjectAttributes{
+		Metadata:        map[string]string{"owner": "b"},
+		ContentLanguage: "en",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	object, _ := adapter.Object("bucket", "report.csv")
+	if object.CacheControl != "no-cache" || object.ContentLanguage != "en" || object.Tags["env"] != "prod" {
+		t.Errorf("unexpected object %+v", object)
+	}
+	if len(object.Metadata) != 1 || object.Metadata["owner"] != "b" {
+		t.Errorf("expected the metadata to be replaced, got %v", object.Metadata)
+	}
+
+	if err := builder.UpdateMetadata("bucket", "missing.csv", ObjectAttributes{CacheControl: "no-cache"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if err := builder.UpdateMetadata("bucket", "", ObjectAttributes{CacheControl: "no-cache"}); err == nil {
+		t.Error("expected the key to be required")
+	}
+}
+
+func TestAttributesAreUnsupported(t *testing.T) {
+	ctx := context.Background()
+	adapter, cleanup := newLocalTestAdapter(t)
+	defer cleanup()
+	builder := New(LocalClient{Root: adapter.Root})
+
+	if _, err := builder.PutWithAttributes(ctx, "bucket", "report.csv", strings.NewReader("a,b"), "", ObjectAttributes{}); err != nil {
+		t.Errorf("expected a plain upload without attributes, got %v", err)
+	}
+	if _, err := builder.PutWithAttributes(ctx, "bucket", "report.csv", strings.NewReader("a,b"), "", ObjectAttributes{CacheControl: "no-cache"}); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("expected ErrUnsupported, got %v", err)
+	}
+	if err := builder.UpdateMetadata("bucket", "report.csv", ObjectAttributes{CacheControl: "no-cache"}); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("expected ErrUnsupported, got %v", err)
+	}
+}
+
+func TestS3PutWithAttributes(t *testing.T) {
+	fake, adapter, stop := newFakeS3(t)
+	defer stop()
+
+	var header http.Header
+	fake.handler = func(w http.ResponseWriter, r *http.Request, body []byte) bool {
+		header = r.Header
+		return false
+	}
+
+	_, err := adapter.PutWithAttributes(context.Background(), "bucket", "report.csv", strings.NewReader("a,b"), ContentTypeCSV, ObjectAttributes{
+		Metadata:        map[string]string{"tenant": "a"},
+		ContentEncoding: "gzip",
+		Tags:            map[string]string{"env": "prod", "team": "data"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header.Get("X-Amz-Meta-Tenant") != "a" || header.Get("Content-Encoding") != "gzip" || header.Get("X-Amz-Tagging") != "env=prod&team=data" {
+		t.Errorf("unexpected headers %v", header)
+	}
+}
+
+func TestS3UpdateMetadata(t *testing.T) {
+	fake, adapter, stop := newFakeS3(t)
+	defer stop()
+
+	var copyHeader http.Header
+	var tagging []byte
+	fake.handler = func(w http.ResponseWriter, r *http.Request, body []byte) bool {
+		switch {
+		case r.Method == http.MethodHead:
+			w.Header().Set("Content-Type", "text/csv")
+			w.Header().Set("Cache-Control", "no-cache")
+			w.Header().Set("X-Amz-Meta-Tenant", "a")
+		case r.Header.Get("X-Amz-Copy-Source") != "":
+			copyHeader = r.Header
+			w.Write([]byte("<CopyObjectResult></CopyObjectResult>"))
+		case r.URL.Query().Get("tagging") == "" && strings.Contains(r.URL.RawQuery, "tagging"):
+			sum := md5.Sum(body)
+			if got := r.Header.Get("Content-MD5"); got != base64.StdEncoding.EncodeToString(sum[:]) {
+				t.Errorf("Content-MD5 = %q, not the md5 of the body", got)
+			}
+			tagging = body
+		default:
+			return false
+		}
+		return true
+	}
+
+	err := adapter.UpdateMetadata(context.Background(), "bucket", "report.csv", ObjectAttributes{
+		ContentLanguage: "en",
+		Tags:            map[string]string{"env": "prod"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the headers which don't change are carried over
+	if copyHeader.Get("X-Amz-Metadata-Directive") != "REPLACE" || copyHeader.Get("X-Amz-Copy-Source") != "/bucket/report.csv" {
+		t.Errorf("unexpected copy headers %v", copyHeader)
+	}
+	for key, want := range map[string]string{"Content-Type": "text/csv", "Cache-Control": "no-cache", "Content-Language": "en", "X-Amz-Meta-Tenant": "a"} {
+		if got := copyHeader.Get(key); got != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+	if !bytes.Contains(tagging, []byte("<TagSet><Tag><Key>env</Key><Value>prod</Value></Tag></TagSet>")) {
+		t.Errorf("unexpected tagging %s", tagging)
+	}
+}
@@ -0,0 +1,164 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	defaultListPageSize = 1000
+)
+
+// ErrIteratorDone : Returned by ObjectIterator.Next when there are no more objects
+var ErrIteratorDone = errors.New("storage: no more items in iterator")
+
+// ListOptions :
+type ListOptions struct {
+	// Prefix only list the objects whose names begin with it
+	Prefix string
+	// Delimiter groups the names containing it after the prefix into a
+	// single "directory" entry, usually "/"
+	Delimiter string
+	// PageToken resumes the listing, it comes from ListResult.NextPageToken
+	PageToken string
+	// PageSize is the maximum number of entries per page, 1000 when zero
+	PageSize int
+}
+
+// ObjectInfo : The provider neutral attributes of an object. For a "directory"
+// entry of a delimited listing only Prefix is set. Listing only fills Name, Size,
+// ContentType and Updated, the rest comes from Stat
+type ObjectInfo struct {
+	Name               string
+	Size               int64
+	ContentType        string
+	ContentDisposition string
+	ContentEncoding    string
+	ContentLanguage    string
+	CacheControl       string
+	ETag               string
+	MD5                []byte
+	CRC32C             uint32 // gcs and memory only
+	Created            time.Time
+	Updated            time.Time
+	Metadata           map[string]string
+	Prefix             string
+}
+
+// ListResult : One page of the listing
+type ListResult struct {
+	Objects       []*ObjectInfo
+	NextPageToken string
+}
+
+// ObjectIterator : Iterate over the objects of a bucket, fetching the pages as needed
+type ObjectIterator struct {
+	ctx     context.Context
+	adapter Adapter
+	bucket  string
+	options ListOptions
+	objects []*ObjectInfo
+	done    bool
+	err     error
+}
+
+// Next : Return the next object, or ErrIteratorDone once the listing is exhausted
+func (it *ObjectIterator) Next() (*ObjectInfo, error) {
+	for len(it.objects) == 0 {
+		if it.err != nil {
+			return nil, it.err
+		}
+		if it.done {
+			return nil, ErrIteratorDone
+		}
+
+		result, err := it.NextPage()
+		if err != nil && err != ErrIteratorDone {
+			return nil, err
+		}
+		if result != nil {
+			it.objects = result.Objects
+		}
+	}
+
+	object := it.objects[0]
+	it.objects = it.objects[1:]
+	return object, nil
+}
+
+// NextPage : Return the next whole page, for the callers which paginate themselves.
+// It must not be mixed with Next
+func (it *ObjectIterator) NextPage() (*ListResult, error) {
+	if it.err != nil {
+		return nil, it.err
+	}
+	if it.done {
+		return nil, ErrIteratorDone
+	}
+
+	result, err := it.adapter.List(it.ctx, it.bucket, it.options)
+	if err != nil {
+		it.err = err
+		return nil, err
+	}
+
+	it.options.PageToken = result.NextPageToken
+	if result.NextPageToken == "" {
+		it.done = true
+	}
+
+	return result, nil
+}
+
+// PageToken : The token of the page the iterator fetches next
+func (it *ObjectIterator) PageToken() string {
+	return it.options.PageToken
+}
+
+// listObjects : Apply the list options over objects sorted by name, for the
+// adapters which have no listing of their own
+func listObjects(objects []*ObjectInfo, options ListOptions) *ListResult {
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].Name < objects[j].Name
+	})
+
+	pageSize := options.PageSize
+	if pageSize <= 0 {
+		pageSize = defaultListPageSize
+	}
+
+	result := new(ListResult)
+	last := ""
+	for _, object := range objects {
+		if !strings.HasPrefix(object.Name, options.Prefix) {
+			continue
+		}
+
+		entry := object
+		name := object.Name
+		if options.Delimiter != "" {
+			rest := strings.TrimPrefix(object.Name, options.Prefix)
+			if i := strings.Index(rest, options.Delimiter); i >= 0 {
+				name = options.Prefix + rest[:i+len(options.Delimiter)]
+				entry = &ObjectInfo{Prefix: name}
+			}
+		}
+
+		if name <= options.PageToken || name == last {
+			continue
+		}
+
+		if len(result.Objects) == pageSize {
+			result.NextPageToken = last
+			break
+		}
+
+		result.Objects = append(result.Objects, entry)
+		last = name
+	}
+
+	return result
+}
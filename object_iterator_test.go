@@ -0,0 +1,144 @@
+package storage
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func listTestObjects(names ...string) []*ObjectInfo {
+	objects := make([]*ObjectInfo, 0, len(names))
+	for _, name := range names {
+		objects = append(objects, &ObjectInfo{Name: name})
+	}
+	return objects
+}
+
+func listEntryNames(result *ListResult) []string {
+	names := make([]string, 0, len(result.Objects))
+	for _, object := range result.Objects {
+		if object.Prefix != "" {
+			names = append(names, object.Prefix)
+			continue
+		}
+		names = append(names, object.Name)
+	}
+	return names
+}
+
+func TestListObjects(t *testing.T) {
+	names := []string{"b.csv", "a.csv", "dir/x.csv", "dir/y.csv", "dir/sub/z.csv", "other/w.csv"}
+
+	tests := []struct {
+		options ListOptions
+		want    []string
+		next    string
+	}{
+		{ListOptions{}, []string{"a.csv", "b.csv", "dir/sub/z.csv", "dir/x.csv", "dir/y.csv", "other/w.csv"}, ""},
+		{ListOptions{Prefix: "dir/"}, []string{"dir/sub/z.csv", "dir/x.csv", "dir/y.csv"}, ""},
+		{ListOptions{Delimiter: "/"}, []string{"a.csv", "b.csv", "dir/", "other/"}, ""},
+		{ListOptions{Prefix: "dir/", Delimiter: "/"}, []string{"dir/sub/", "dir/x.csv", "dir/y.csv"}, ""},
+		{ListOptions{PageSize: 2}, []string{"a.csv", "b.csv"}, "b.csv"},
+		{ListOptions{PageSize: 2, PageToken: "b.csv"}, []string{"dir/sub/z.csv", "dir/x.csv"}, "dir/x.csv"},
+		{ListOptions{PageSize: 2, Delimiter: "/", PageToken: "b.csv"}, []string{"dir/", "other/"}, ""},
+	}
+
+	for _, tt := range tests {
+		result := listObjects(listTestObjects(names...), tt.options)
+		if got := listEntryNames(result); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%+v: listed %v, want %v", tt.options, got, tt.want)
+		}
+		if result.NextPageToken != tt.next {
+			t.Errorf("%+v: next page token %q, want %q", tt.options, result.NextPageToken, tt.next)
+		}
+	}
+}
+
+func TestObjectIterator(t *testing.T) {
+	ctx := context.Background()
+	adapter := NewMemoryAdapter()
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		if _, err := adapter.UploadReader("bucket", name, strings.NewReader(name), ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	builder := New(adapter)
+
+	it := builder.List(ctx, "bucket", ListOptions{PageSize: 2})
+	var names []string
+	for {
+		object, err := it.Next()
+		if err == ErrIteratorDone {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, object.Name)
+	}
+	if want := []string{"a", "b", "c", "d", "e"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("iterated %v, want %v", names, want)
+	}
+
+	it = builder.List(ctx, "bucket", ListOptions{PageSize: 2})
+	pages := 0
+	for {
+		_, err := it.NextPage()
+		if err == ErrIteratorDone {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		pages++
+	}
+	if pages != 3 {
+		t.Errorf("expected 3 pages, got %d", pages)
+	}
+
+	if _, err := builder.List(ctx, "", ListOptions{}).Next(); err == nil || err == ErrIteratorDone {
+		t.Errorf("expected an empty bucket to be refused, got %v", err)
+	}
+}
+
+func TestLocalList(t *testing.T) {
+	adapter, cleanup := newLocalTestAdapter(t)
+	defer cleanup()
+
+	for _, name := range []string{"report.csv", "dir/a.csv", "dir/b.pdf"} {
+		if _, err := adapter.UploadReader("bucket", name, strings.NewReader("data"), ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result, err := adapter.List(context.Background(), "bucket", ListOptions{Delimiter: "/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := listEntryNames(result), []string{"dir/", "report.csv"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("listed %v, want %v", got, want)
+	}
+
+	result, err = adapter.List(context.Background(), "bucket", ListOptions{Prefix: "dir/", PageSize: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Objects) != 1 || result.Objects[0].Name != "dir/a.csv" || result.Objects[0].Size != 4 {
+		t.Errorf("unexpected first page %+v", result.Objects)
+	}
+	if result.Objects[0].ContentType != "text/csv" {
+		t.Errorf("content type = %q, want text/csv", result.Objects[0].ContentType)
+	}
+	if result.NextPageToken != "dir/a.csv" {
+		t.Errorf("next page token %q", result.NextPageToken)
+	}
+
+	result, err = adapter.List(context.Background(), "missing", ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Objects) != 0 {
+		t.Errorf("expected an empty listing for a missing bucket, got %d objects", len(result.Objects))
+	}
+}
@@ -0,0 +1,186 @@
+package storage
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+const (
+	googleStorageHost = "storage.googleapis.com"
+	aliyunStorageHost = ".aliyuncs.com"
+)
+
+// ObjectRef : Identify an object independently of the url it is served from.
+// Provider and Bucket are empty when the url doesn't tell them, e.g. a custom domain
+type ObjectRef struct {
+	Provider string
+	Bucket   string
+	Key      string
+}
+
+// String : The gs://, oss://, s3:// or azblob:// form of the reference
+func (ref ObjectRef) String() string {
+	switch ref.Provider {
+	case GCS:
+		return fmt.Sprintf("gs://%s/%s", ref.Bucket, ref.Key)
+	case ALIYUN:
+		return fmt.Sprintf("oss://%s/%s", ref.Bucket, ref.Key)
+	case MEMORY:
+		return fmt.Sprintf("%s%s/%s", memoryScheme, ref.Bucket, ref.Key)
+	case S3:
+		return fmt.Sprintf("%s%s/%s", s3Scheme, ref.Bucket, ref.Key)
+	case AZURE:
+		return fmt.Sprintf("%s%s/%s", azureScheme, ref.Bucket, ref.Key)
+	}
+
+	return fmt.Sprintf("%s/%s", ref.Bucket, ref.Key)
+}
+
+// ParseObjectURL : Parse every url form the library produces, plus the signed
+// urls and the gs://, oss://, s3:// and azblob:// uris. The query string is
+// ignored and the key is unescaped, unless the url has the key as it is like the
+// urls of the previous versions. The url of a custom domain only yields the key
+func ParseObjectURL(rawURL string) (ObjectRef, error) {
+	ref := ObjectRef{}
+
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		// a key with a literal "%", e.g. https://storage.googleapis.com/bucket/100%.csv
+		var rawErr error
+		if u, rawErr = url.Parse(strings.Replace(strings.TrimSpace(rawURL), "%", "%25", -1)); rawErr != nil {
+			return ref, fmt.Errorf("storage: invalid object url %q: %v", rawURL, err)
+		}
+	}
+
+	host := strings.ToLower(u.Hostname())
+	path := strings.TrimPrefix(objectURLPath(u), "/")
+
+	switch {
+	case u.Scheme == "gs":
+		ref = ObjectRef{Provider: GCS, Bucket: u.Host, Key: path}
+
+	case u.Scheme == "oss":
+		ref = ObjectRef{Provider: ALIYUN, Bucket: u.Host, Key: path}
+
+	case u.Scheme+"://" == s3Scheme:
+		ref = ObjectRef{Provider: S3, Bucket: u.Host, Key: path}
+
+	case u.Scheme+"://" == azureScheme:
+		ref = ObjectRef{Provider: AZURE, Bucket: u.Host, Key: path}
+
+	case u.Scheme+"://" == memoryScheme:
+		ref = ObjectRef{Provider: MEMORY, Bucket: u.Host, Key: path}
+
+	case u.Scheme+"://" == localScheme:
+		// the bucket is the last directory before the key, which only the
+		// adapter knows from its root
+		ref = ObjectRef{Provider: LOCAL, Key: objectURLPath(u)}
+
+	case u.Scheme != "http" && u.Scheme != "https":
+		return ref, fmt.Errorf("storage: invalid object url %q", rawURL)
+
+	// https://storage.googleapis.com/bucket/key
+	case host == googleStorageHost || host == "storage.cloud.google.com":
+		ref.Provider = GCS
+		ref.Bucket, ref.Key = splitBucketPath(path)
+
+	// https://bucket.storage.googleapis.com/key
+	case strings.HasSuffix(host, "."+googleStorageHost):
+		ref = ObjectRef{Provider: GCS, Bucket: strings.TrimSuffix(host, "."+googleStorageHost), Key: path}
+
+	// https://bucket.oss-cn-hangzhou.aliyuncs.com/key or the path style
+	// https://oss-cn-hangzhou.aliyuncs.com/bucket/key, the bucket names have no dot
+	case strings.HasSuffix(host, aliyunStorageHost):
+		ref.Provider = ALIYUN
+		labels := strings.Split(strings.TrimSuffix(host, aliyunStorageHost), ".")
+		if len(labels) == 1 {
+			ref.Bucket, ref.Key = splitBucketPath(path)
+		} else {
+			ref.Bucket = labels[0]
+			ref.Key = path
+		}
+
+	// https://bucket.s3.us-east-1.amazonaws.com/key or the path style
+	// https://s3.us-east-1.amazonaws.com/bucket/key. The bucket is every label
+	// before the s3 one, which the dotted bucket names can contain as well
+	case strings.HasSuffix(host, s3StorageHost):
+		ref.Provider = S3
+		labels := strings.Split(strings.TrimSuffix(host, s3StorageHost), ".")
+		i := len(labels) - 1
+		for i > 0 && labels[i] != "s3" && !strings.HasPrefix(labels[i], "s3-") {
+			i--
+		}
+		if i == 0 {
+			ref.Bucket, ref.Key = splitBucketPath(path)
+		} else {
+			ref.Bucket = strings.Join(labels[:i], ".")
+			ref.Key = path
+		}
+
+	// https://account.blob.core.windows.net/container/key
+	case strings.HasSuffix(host, azureStorageHost):
+		ref.Provider = AZURE
+		ref.Bucket, ref.Key = splitBucketPath(path)
+
+	default:
+		ref.Key = path
+	}
+
+	if ref.Key == "" {
+		return ref, fmt.Errorf("storage: object url %q has no key", rawURL)
+	}
+
+	return ref, nil
+}
+
+// escapeObjectKey : Escape the key in the urls of the objects, so ParseObjectURL
+// gives it back as it is, e.g. with a "?", "#" or "%" in it
+func escapeObjectKey(key string) string {
+	return s3Escape(key, false)
+}
+
+// objectURLPath : The unescaped path of u, or its raw path when it escapes a
+// byte which escapeObjectKey keeps as it is, e.g. "%41", since unescaping would
+// then change a key which was not escaped
+func objectURLPath(u *url.URL) string {
+	for i := 0; i+2 < len(u.RawPath); i++ {
+		if u.RawPath[i] != '%' {
+			continue
+		}
+		b, err := hex.DecodeString(u.RawPath[i+1 : i+3])
+		if err != nil || escapeObjectKey(string(b)) == string(b) {
+			return u.RawPath
+		}
+	}
+	return u.Path
+}
+
+// splitBucketPath : The bucket and key of a path style url
+func splitBucketPath(path string) (string, string) {
+	parts := strings.SplitN(path, "/", 2)
+	if len(parts) == 1 {
+		return parts[0], ""
+	}
+	return parts[0], parts[1]
+}
+
+// objectKeyFromURL : Extract the key of fileURL, which must be in bucket when
+// the url tells it. A plain key is returned as it is
+func objectKeyFromURL(bucket, fileURL string) (string, error) {
+	if !strings.Contains(fileURL, "://") {
+		return fileURL, nil
+	}
+
+	ref, err := ParseObjectURL(fileURL)
+	if err != nil {
+		return "", err
+	}
+
+	if ref.Bucket != "" && ref.Bucket != bucket {
+		return "", fmt.Errorf("storage: object url %q is not in bucket %q", fileURL, bucket)
+	}
+
+	return ref.Key, nil
+}
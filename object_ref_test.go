@@ -0,0 +1,239 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	s "cloud.google.com/go/storage"
+)
+
+func TestParseObjectURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want ObjectRef
+	}{
+		{"gs://bucket/dir/report.pdf", ObjectRef{GCS, "bucket", "dir/report.pdf"}},
+		{"oss://bucket/dir/report.pdf", ObjectRef{ALIYUN, "bucket", "dir/report.pdf"}},
+		{"mem://bucket/dir/report.pdf", ObjectRef{MEMORY, "bucket", "dir/report.pdf"}},
+		{"https://storage.googleapis.com/bucket/dir/report.pdf", ObjectRef{GCS, "bucket", "dir/report.pdf"}},
+		{"https://storage.googleapis.com/bucket/report.pdf?X-Goog-Signature=abc", ObjectRef{GCS, "bucket", "report.pdf"}},
+		{"https://bucket.storage.googleapis.com/report.pdf", ObjectRef{GCS, "bucket", "report.pdf"}},
+		{"https://bucket.oss-cn-hangzhou.aliyuncs.com/report.pdf", ObjectRef{ALIYUN, "bucket", "report.pdf"}},
+		{"https://oss-data.oss-cn-hangzhou.aliyuncs.com/report.pdf", ObjectRef{ALIYUN, "oss-data", "report.pdf"}},
+		{"https://oss-cn-hangzhou.aliyuncs.com/bucket/report.pdf", ObjectRef{ALIYUN, "bucket", "report.pdf"}},
+		{"https://bucket.s3.us-east-1.amazonaws.com/dir/report.pdf", ObjectRef{S3, "bucket", "dir/report.pdf"}},
+		{"https://s3-logs.s3.us-east-1.amazonaws.com/dir/report.pdf", ObjectRef{S3, "s3-logs", "dir/report.pdf"}},
+		{"https://my.dotted.bucket.s3.amazonaws.com/report.pdf", ObjectRef{S3, "my.dotted.bucket", "report.pdf"}},
+		{"https://bucket.s3-us-west-2.amazonaws.com/report.pdf", ObjectRef{S3, "bucket", "report.pdf"}},
+		{"https://s3.us-east-1.amazonaws.com/bucket/report.pdf", ObjectRef{S3, "bucket", "report.pdf"}},
+		{"https://s3-us-west-2.amazonaws.com/bucket/report.pdf", ObjectRef{S3, "bucket", "report.pdf"}},
+		{"https://account.blob.core.windows.net/container/dir/report.pdf", ObjectRef{AZURE, "container", "dir/report.pdf"}},
+		{"https://cdn.example.com/dir/report.pdf?X-Amz-Signature=abc", ObjectRef{"", "", "dir/report.pdf"}},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseObjectURL(tt.url)
+		if err != nil {
+			t.Errorf("ParseObjectURL(%q): %v", tt.url, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseObjectURL(%q) = %+v, want %+v", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestParseBaselineObjectURL(t *testing.T) {
+	// the urls of the previous versions have the key as it is
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://storage.googleapis.com/bucket/100%.csv", "100%.csv"},
+		{"https://bucket.oss-cn-hangzhou.aliyuncs.com/dir/50%off.csv", "dir/50%off.csv"},
+		{"https://storage.googleapis.com/bucket/dir/with space.csv", "dir/with space.csv"},
+		{"https://storage.googleapis.com/bucket/report%41.csv", "report%41.csv"},
+		{"https://storage.googleapis.com/bucket/with%20space.csv", "with space.csv"},
+	}
+
+	for _, tt := range tests {
+		got, err := objectKeyFromURL("bucket", tt.url)
+		if err != nil {
+			t.Errorf("objectKeyFromURL(%q): %v", tt.url, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("objectKeyFromURL(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestParseObjectURLWithoutKey(t *testing.T) {
+	for _, url := range []string{
+		"https://storage.googleapis.com/bucket",
+		"https://s3.us-east-1.amazonaws.com/bucket",
+		"https://oss-cn-hangzhou.aliyuncs.com/bucket",
+		"gs://bucket",
+		"ftp://host/report.pdf",
+	} {
+		if _, err := ParseObjectURL(url); err == nil {
+			t.Errorf("ParseObjectURL(%q): expected an error", url)
+		}
+	}
+}
+
+func TestObjectRefString(t *testing.T) {
+	tests := []struct {
+		ref  ObjectRef
+		want string
+	}{
+		{ObjectRef{GCS, "bucket", "report.pdf"}, "gs://bucket/report.pdf"},
+		{ObjectRef{ALIYUN, "bucket", "report.pdf"}, "oss://bucket/report.pdf"},
+		{ObjectRef{MEMORY, "bucket", "report.pdf"}, "mem://bucket/report.pdf"},
+	}
+	for _, tt := range tests {
+		if got := tt.ref.String(); got != tt.want {
+			t.Errorf("%+v.String() = %q, want %q", tt.ref, got, tt.want)
+		}
+		if ref, err := ParseObjectURL(tt.want); err != nil || ref != tt.ref {
+			t.Errorf("ParseObjectURL(%q) = %+v, %v", tt.want, ref, err)
+		}
+	}
+}
+
+func TestObjectKeyFromURL(t *testing.T) {
+	if key, err := objectKeyFromURL("bucket", "dir/report.pdf"); err != nil || key != "dir/report.pdf" {
+		t.Errorf("a plain key = %q, %v", key, err)
+	}
+	if key, err := objectKeyFromURL("bucket", "https://storage.googleapis.com/bucket/report.pdf"); err != nil || key != "report.pdf" {
+		t.Errorf("a gcs url = %q, %v", key, err)
+	}
+	if _, err := objectKeyFromURL("bucket", "https://storage.googleapis.com/other/report.pdf"); err == nil {
+		t.Error("expected a url of another bucket to be refused")
+	}
+}
+
+func TestDeleteObject(t *testing.T) {
+	adapter, cleanup := newLocalTestAdapter(t)
+	defer cleanup()
+
+	for _, builder := range []*Builder{New(NewMemoryAdapter()), New(LocalClient{Root: adapter.Root})} {
+		if _, err := builder.UploadReader("bucket", "dir/report.csv", strings.NewReader("a,b"), ""); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := builder.TemporaryServingObject("bucket", "dir/report.csv", time.Now().Add(time.Hour), nil); err != nil {
+			t.Errorf("TemporaryServingObject: %v", err)
+		}
+
+		if err := builder.DeleteObject("bucket", "dir/report.csv"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := builder.Stat("bucket", "dir/report.csv"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected the object to be deleted, got %v", err)
+		}
+		if _, err := builder.TemporaryServingObject("bucket", "dir/report.csv", time.Now().Add(time.Hour), nil); !errors.Is(err, ErrNotFound) {
+			t.Errorf("expected ErrNotFound, got %v", err)
+		}
+	}
+}
+
+func TestLocalDeleteFileUsingURL(t *testing.T) {
+	ctx := context.Background()
+	adapter, cleanup := newLocalTestAdapter(t)
+	defer cleanup()
+
+	result, err := adapter.Put(ctx, "bucket", "dir/report.csv", strings.NewReader("a,b"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := adapter.DeleteFileUsingURLWithContext(ctx, "bucket", result.URL); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := adapter.Stat(ctx, "bucket", "dir/report.csv"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected the file to be deleted through its url, got %v", err)
+	}
+}
+
+// roundTripKeys : The keys which are cut or fail to parse when they are not escaped
+var roundTripKeys = []string{"report?v=1.csv", "report#1.csv", "100%.csv", "dir/with space.csv"}
+
+func TestObjectURLRoundTrip(t *testing.T) {
+	gcs := &GCSAdapter{}
+	s3 := &S3Adapter{Region: "us-east-1"}
+
+	for _, key := range roundTripKeys {
+		s3URL, err := s3.objectURL("bucket", key)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, url := range []string{
+			gcs.uploadResult(&s.ObjectAttrs{Bucket: "bucket", Name: key}).URL,
+			getAliyunFileURL("oss-cn-hangzhou.aliyuncs.com", "bucket", key),
+			getMemoryFileURL("bucket", key),
+			s3URL.String(),
+		} {
+			got, err := objectKeyFromURL("bucket", url)
+			if err != nil {
+				t.Errorf("objectKeyFromURL(%q): %v", url, err)
+				continue
+			}
+			if got != key {
+				t.Errorf("objectKeyFromURL(%q) = %q, want %q", url, got, key)
+			}
+		}
+	}
+}
+
+func TestDeleteFileUsingURLRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	memory := NewMemoryAdapter()
+
+	local, cleanup := newLocalTestAdapter(t)
+	defer cleanup()
+
+	for _, adapter := range []Adapter{memory, local} {
+		for _, key := range roundTripKeys {
+			if _, err := adapter.Put(ctx, "bucket", "report", strings.NewReader("other"), ""); err != nil {
+				t.Fatal(err)
+			}
+			result, err := adapter.Put(ctx, "bucket", key, strings.NewReader("data"), "")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := adapter.DeleteFileUsingURLWithContext(ctx, "bucket", result.URL); err != nil {
+				t.Fatalf("%T DeleteFileUsingURL(%q): %v", adapter, result.URL, err)
+			}
+			if _, err := adapter.Stat(ctx, "bucket", key); err == nil {
+				t.Errorf("%T: %q is not deleted through %q", adapter, key, result.URL)
+			}
+			if _, err := adapter.Stat(ctx, "bucket", "report"); err != nil {
+				t.Errorf("%T: deleting %q removed another object: %v", adapter, key, err)
+			}
+		}
+	}
+}
+
+func TestDeleteMissingObject(t *testing.T) {
+	ctx := context.Background()
+	local, cleanup := newLocalTestAdapter(t)
+	defer cleanup()
+	_, gcs, stopGCS := newFakeGCS(t)
+	defer stopGCS()
+	_, s3, stopS3 := newFakeS3(t)
+	defer stopS3()
+	_, azure, stopAzure := newFakeAzure(t)
+	defer stopAzure()
+
+	// deleting a missing object succeeds on every adapter
+	for _, adapter := range []Adapter{NewMemoryAdapter(), local, gcs, s3, azure} {
+		if err := adapter.DeleteObject(ctx, "bucket", "missing.csv"); err != nil {
+			t.Errorf("%T: expected deleting a missing object to succeed, got %v", adapter, err)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package storage
+
+import (
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// defaultMemoryBucket : The bucket of mem:// when it names none
+const defaultMemoryBucket = "default"
+
+// Open : Open the bucket of a connection string, so a storage target can be
+// configured through a single environment variable, e.g.
+//
+//	gs://bucket?credentials=/path/to/service-account.json&endpoint=http://localhost:4443
+//	oss://bucket?endpoint=oss-cn-hangzhou.aliyuncs.com&access_key_id=...&access_key_secret=...
+//	s3://bucket?region=us-east-1&endpoint=http://localhost:9000&path_style=true&access_key_id=...&secret_access_key=...
+//	azblob://container?account_name=...&account_key=...&endpoint=...
+//	file:///var/data
+//	mem://bucket
+//
+// The file url is the directory of the bucket, whose parent is the root of the
+// local adapter. An unknown parameter is an error, so a typo doesn't go unnoticed
+func Open(rawURL string) (*BucketHandle, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid connection string: %v", ErrInvalidClient, err)
+	}
+
+	params := connectionParams(u.Query())
+	bucket := u.Host
+
+	var config Config
+	switch u.Scheme + "://" {
+	case "gs://":
+		config = GoogleCloudStorageClient{
+			CredentialsFile: params.get("credentials"),
+			Endpoint:        params.get("endpoint"),
+		}
+
+	case "oss://":
+		config = AliyunClient{
+			Endpoint:        params.get("endpoint"),
+			AccessKeyID:     params.get("access_key_id"),
+			AccessKeySecret: params.get("access_key_secret"),
+			SecurityToken:   params.get("security_token"),
+		}
+
+	case s3Scheme:
+		pathStyle := false
+		if value := params.get("path_style"); value != "" {
+			if pathStyle, err = strconv.ParseBool(value); err != nil {
+				return nil, fmt.Errorf("%w: invalid path_style %q", ErrInvalidClient, value)
+			}
+		}
+		config = S3Client{
+			Endpoint:        params.get("endpoint"),
+			Region:          params.get("region"),
+			AccessKeyID:     params.get("access_key_id"),
+			SecretAccessKey: params.get("secret_access_key"),
+			SessionToken:    params.get("session_token"),
+			PathStyle:       pathStyle,
+		}
+
+	case azureScheme:
+		config = AzureClient{
+			AccountName: params.get("account_name"),
+			AccountKey:  params.get("account_key"),
+			Endpoint:    params.get("endpoint"),
+		}
+
+	case localScheme:
+		path := filepath.Clean(filepath.FromSlash(u.Path))
+		bucket = filepath.Base(path)
+		config = LocalClient{Root: filepath.Dir(path)}
+
+	case memoryScheme:
+		if bucket == "" {
+			bucket = defaultMemoryBucket
+		}
+		config = MemoryClient{}
+
+	default:
+		return nil, fmt.Errorf("%w: the scheme %q not supported", ErrInvalidClient, u.Scheme)
+	}
+
+	if err := params.unknown(); err != nil {
+		return nil, err
+	}
+
+	if bucket == "" {
+		return nil, fmt.Errorf("%w: the %s connection string has no bucket", ErrInvalidClient, u.Scheme)
+	}
+
+	builder := New(config)
+	handle := builder.Bucket(bucket)
+	if handle.err != nil {
+		// nobody else holds the builder to release its client
+		builder.Close()
+		return nil, handle.err
+	}
+
+	return handle, nil
+}
+
+// connectionParams : The query of the connection string, the parameters are
+// removed as they are read so the unknown ones are left
+type connectionParams url.Values
+
+func (params connectionParams) get(name string) string {
+	value := url.Values(params).Get(name)
+	delete(params, name)
+	return value
+}
+
+func (params connectionParams) unknown() error {
+	if len(params) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return fmt.Errorf("%w: unknown connection parameters %s", ErrInvalidClient, strings.Join(names, ", "))
+}
@@ -0,0 +1,121 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOpen(t *testing.T) {
+	tests := []struct {
+		url    string
+		bucket string
+		check  func(adapter Adapter) bool
+	}{
+		{"mem://bucket", "bucket", func(adapter Adapter) bool {
+			_, ok := adapter.(*MemoryAdapter)
+			return ok
+		}},
+		{"mem://", defaultMemoryBucket, func(adapter Adapter) bool {
+			_, ok := adapter.(*MemoryAdapter)
+			return ok
+		}},
+		{"file:///var/data/bucket", "bucket", func(adapter Adapter) bool {
+			local, ok := adapter.(*LocalAdapter)
+			return ok && local.Root == filepath.FromSlash("/var/data")
+		}},
+		{"s3://bucket?region=eu-west-1&endpoint=http://localhost:9000&path_style=true&access_key_id=AKID&secret_access_key=secret", "bucket", func(adapter Adapter) bool {
+			s3, ok := adapter.(*S3Adapter)
+			return ok && s3.Region == "eu-west-1" && s3.Endpoint == "http://localhost:9000" && s3.PathStyle &&
+				s3.AccessKeyID == "AKID" && s3.SecretAccessKey == "secret"
+		}},
+		{"azblob://container?account_name=account&account_key=" + azureTestKey, "container", func(adapter Adapter) bool {
+			azure, ok := adapter.(*AzureBlobAdapter)
+			return ok && azure.AccountName == "account" && azure.AccountKey == azureTestKey
+		}},
+		{"oss://bucket?endpoint=oss-cn-hangzhou.aliyuncs.com&access_key_id=id&access_key_secret=secret", "bucket", func(adapter Adapter) bool {
+			aliyun, ok := adapter.(*AliyunAdapter)
+			return ok && aliyun.Endpoint == "oss-cn-hangzhou.aliyuncs.com" && aliyun.AccessKeyID == "id"
+		}},
+	}
+
+	for _, tt := range tests {
+		bucket, err := Open(tt.url)
+		if err != nil {
+			t.Errorf("Open(%q): %v", tt.url, err)
+			continue
+		}
+		if bucket.Name() != tt.bucket {
+			t.Errorf("Open(%q): bucket %q, want %q", tt.url, bucket.Name(), tt.bucket)
+		}
+		if !tt.check(bucket.Builder().adapter) {
+			t.Errorf("Open(%q): unexpected adapter %+v", tt.url, bucket.Builder().adapter)
+		}
+		bucket.Close()
+	}
+}
+
+func TestOpenInvalid(t *testing.T) {
+	for _, url := range []string{
+		"ftp://bucket",
+		"mem://bucket?credential=x",
+		"s3://bucket?region=us-east-1&path_style=maybe",
+		"s3://?region=us-east-1",
+		"azblob://container?account_name=account",
+		"://",
+	} {
+		if _, err := Open(url); !errors.Is(err, ErrInvalidClient) {
+			t.Errorf("Open(%q): expected ErrInvalidClient, got %v", url, err)
+		}
+	}
+
+	for _, url := range []string{"file:///", "s3://Invalid_Bucket?region=us-east-1", "s3://b?region=us-east-1"} {
+		if _, err := Open(url); err == nil {
+			t.Errorf("Open(%q): expected the bucket name to be refused", url)
+		}
+	}
+}
+
+func TestOpenUnknownParameters(t *testing.T) {
+	_, err := Open("mem://bucket?regoin=us-east-1&zone=a")
+	if err == nil || !strings.Contains(err.Error(), "regoin, zone") {
+		t.Errorf("expected the unknown parameters in the error, got %v", err)
+	}
+}
+
+func TestBucketHandle(t *testing.T) {
+	ctx := context.Background()
+	bucket, err := Open("mem://bucket")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bucket.Close()
+
+	if _, err := bucket.Upload(ctx, "dir/report.csv", strings.NewReader("a,b")); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := bucket.Read(ctx, "dir/report.csv")
+	if err != nil || string(data) != "a,b" {
+		t.Errorf("read %q, %v", data, err)
+	}
+	if isExist, err := bucket.Exists(ctx, "dir/report.csv"); err != nil || !isExist {
+		t.Errorf("Exists = %v, %v", isExist, err)
+	}
+	if object, err := bucket.List(ctx, ListOptions{}).Next(); err != nil || object.Name != "dir/report.csv" {
+		t.Errorf("listed %+v, %v", object, err)
+	}
+	if _, err := bucket.SignURL(ctx, "dir/report.csv", time.Now().Add(time.Hour)); err != nil {
+		t.Errorf("SignURL: %v", err)
+	}
+
+	if err := bucket.Delete(ctx, "dir/report.csv"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bucket.Stat(ctx, "dir/report.csv"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,152 @@
+package storage
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// PolicyOptions : The conditions of a browser form upload, checked by the provider.
+// Key is the exact key of the object, or with KeyPrefix the client may choose any
+// key starting with the prefix, ${filename} by default. With ContentTypePrefix
+// the form must send its own Content-Type field
+type PolicyOptions struct {
+	Expires            time.Time
+	ContentLengthRange ContentLengthRange
+	ContentTypePrefix  string
+	KeyPrefix          string
+	SuccessRedirect    string
+}
+
+// ContentLengthRange : The size of the upload in bytes, applied when Max is set
+type ContentLengthRange struct {
+	Min int64
+	Max int64
+}
+
+// PostForm : The url the form posts to, and the fields it must send before the file
+type PostForm struct {
+	URL    string
+	Fields map[string]string
+}
+
+// PostPolicySigner : Implemented by the adapters which can sign form uploads
+type PostPolicySigner interface {
+	PostPolicy(ctx context.Context, bucket, key string, options PolicyOptions) (*PostForm, error)
+}
+
+var _ PostPolicySigner = &GCSAdapter{}
+var _ PostPolicySigner = &AliyunAdapter{}
+var _ PostPolicySigner = &S3Adapter{}
+
+// PostPolicy : Sign a policy for a browser form upload, so the limits are enforced
+// by the provider
+func (b *Builder) PostPolicy(bucket, key string, options PolicyOptions) (*PostForm, error) {
+	return b.PostPolicyWithContext(context.Background(), bucket, key, options)
+}
+
+// PostPolicyWithContext :
+func (b *Builder) PostPolicyWithContext(ctx context.Context, bucket, key string, options PolicyOptions) (*PostForm, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	var (
+		errKeyIsRequired    = errors.New("storage: key or key prefix is required")
+		errBucketIsRequired = errors.New("storage: bucket is required")
+		errInvalidExpires   = errors.New("storage: expires must be in the future")
+		errInvalidRange     = errors.New("storage: invalid content length range")
+	)
+
+	if len(key) == 0 && len(options.KeyPrefix) == 0 {
+		return nil, errKeyIsRequired
+	}
+
+	if len(key) > 0 && !strings.HasPrefix(key, options.KeyPrefix) {
+		return nil, fmt.Errorf("storage: key %q doesn't start with the key prefix %q", key, options.KeyPrefix)
+	}
+
+	if len(bucket) == 0 {
+		return nil, errBucketIsRequired
+	}
+
+	if !options.Expires.After(time.Now()) {
+		return nil, errInvalidExpires
+	}
+
+	lengthRange := options.ContentLengthRange
+	if lengthRange.Min < 0 || lengthRange.Max < 0 || (lengthRange.Max > 0 && lengthRange.Min > lengthRange.Max) {
+		return nil, errInvalidRange
+	}
+
+	signer, ok := b.adapter.(PostPolicySigner)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T can't sign post policies", ErrUnsupported, b.adapter)
+	}
+
+	return signer.PostPolicy(ctx, bucket, key, options)
+}
+
+// postPolicy : The policy document shared by the providers, the fields the
+// provider adds for its signature are given in fields and added to the conditions
+type postPolicy struct {
+	bucket     string
+	options    PolicyOptions
+	fields     map[string]string
+	conditions []interface{}
+}
+
+func newPostPolicy(bucket, key string, options PolicyOptions) *postPolicy {
+	policy := &postPolicy{bucket: bucket, options: options, fields: make(map[string]string)}
+	policy.conditions = append(policy.conditions, map[string]string{"bucket": bucket})
+
+	if key == "" {
+		policy.fields["key"] = options.KeyPrefix + "${filename}"
+	} else {
+		policy.fields["key"] = key
+	}
+	if options.KeyPrefix != "" {
+		policy.conditions = append(policy.conditions, []interface{}{"starts-with", "$key", options.KeyPrefix})
+	} else {
+		policy.conditions = append(policy.conditions, map[string]string{"key": key})
+	}
+
+	if options.ContentLengthRange.Max > 0 {
+		policy.conditions = append(policy.conditions, []interface{}{"content-length-range", options.ContentLengthRange.Min, options.ContentLengthRange.Max})
+	}
+
+	if options.ContentTypePrefix != "" {
+		policy.conditions = append(policy.conditions, []interface{}{"starts-with", "$Content-Type", options.ContentTypePrefix})
+	}
+
+	if options.SuccessRedirect != "" {
+		policy.set("success_action_redirect", options.SuccessRedirect)
+	}
+
+	return policy
+}
+
+// set : Add a field the form sends as it is
+func (policy *postPolicy) set(name, value string) {
+	policy.fields[name] = value
+	policy.conditions = append(policy.conditions, map[string]string{name: value})
+}
+
+// encode : The base64 policy document, which is the string the providers sign
+func (policy *postPolicy) encode(expirationFormat string) (string, error) {
+	document, err := json.Marshal(struct {
+		Expiration string        `json:"expiration"`
+		Conditions []interface{} `json:"conditions"`
+	}{
+		Expiration: policy.options.Expires.UTC().Format(expirationFormat),
+		Conditions: policy.conditions,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(document), nil
+}
@@ -0,0 +1,141 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func decodePolicy(t *testing.T, document string) []interface{} {
+	data, err := base64.StdEncoding.DecodeString(document)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var policy struct {
+		Expiration string        `json:"expiration"`
+		Conditions []interface{} `json:"conditions"`
+	}
+	if err := json.Unmarshal(data, &policy); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := time.Parse("2006-01-02T15:04:05.000Z", policy.Expiration); err != nil {
+		t.Errorf("invalid expiration: %v", err)
+	}
+	return policy.Conditions
+}
+
+func hasCondition(conditions []interface{}, want interface{}) bool {
+	for _, condition := range conditions {
+		if reflect.DeepEqual(condition, want) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestPostPolicyIsValidated(t *testing.T) {
+	expires := time.Now().Add(time.Hour)
+	s3 := New(S3Client{Region: "us-east-1", AccessKeyID: "AKID", SecretAccessKey: "secret"})
+
+	tests := []struct {
+		key     string
+		options PolicyOptions
+	}{
+		{"", PolicyOptions{Expires: expires}},
+		{"other/report.csv", PolicyOptions{Expires: expires, KeyPrefix: "uploads/"}},
+		{"report.csv", PolicyOptions{Expires: time.Now().Add(-time.Minute)}},
+		{"report.csv", PolicyOptions{Expires: expires, ContentLengthRange: ContentLengthRange{Min: 10, Max: 5}}},
+		{"report.csv", PolicyOptions{Expires: expires, ContentLengthRange: ContentLengthRange{Min: -1}}},
+	}
+	for _, tt := range tests {
+		if _, err := s3.PostPolicy("bucket", tt.key, tt.options); err == nil {
+			t.Errorf("%q %+v: expected the policy to be refused", tt.key, tt.options)
+		}
+	}
+
+	if _, err := New(NewMemoryAdapter()).PostPolicy("bucket", "report.csv", PolicyOptions{Expires: expires}); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("expected ErrUnsupported, got %v", err)
+	}
+}
+
+func TestS3PostPolicy(t *testing.T) {
+	adapter := &S3Adapter{Region: "eu-west-1", AccessKeyID: "AKID", SecretAccessKey: "secret", PathStyle: true, Endpoint: "http://localhost:9000"}
+
+	form, err := adapter.PostPolicy(context.Background(), "bucket", "", PolicyOptions{
+		Expires:            time.Now().Add(time.Hour),
+		ContentLengthRange: ContentLengthRange{Max: 1 << 20},
+		ContentTypePrefix:  "image/",
+		KeyPrefix:          "uploads/",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if form.URL != "http://localhost:9000/bucket" {
+		t.Errorf("unexpected url %q", form.URL)
+	}
+	if form.Fields["key"] != "uploads/${filename}" || form.Fields["x-amz-algorithm"] != "AWS4-HMAC-SHA256" {
+		t.Errorf("unexpected fields %v", form.Fields)
+	}
+
+	conditions := decodePolicy(t, form.Fields["policy"])
+	for _, want := range []interface{}{
+		map[string]interface{}{"bucket": "bucket"},
+		[]interface{}{"starts-with", "$key", "uploads/"},
+		[]interface{}{"content-length-range", float64(0), float64(1 << 20)},
+		[]interface{}{"starts-with", "$Content-Type", "image/"},
+		map[string]interface{}{"x-amz-credential": form.Fields["x-amz-credential"]},
+	} {
+		if !hasCondition(conditions, want) {
+			t.Errorf("the policy misses %v: %v", want, conditions)
+		}
+	}
+
+	// https://docs.aws.amazon.com/AmazonS3/latest/API/sigv4-HTTPPOSTConstructPolicy.html
+	date, err := time.Parse(s3DateFormat, form.Fields["x-amz-date"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := hmacSHA256([]byte("AWS4secret"), date.Format("20060102"))
+	key = hmacSHA256(key, "eu-west-1")
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	if want := hex.EncodeToString(hmacSHA256(key, form.Fields["policy"])); form.Fields["x-amz-signature"] != want {
+		t.Errorf("x-amz-signature = %q, want %q", form.Fields["x-amz-signature"], want)
+	}
+}
+
+func TestAliyunPostPolicy(t *testing.T) {
+	adapter := &AliyunAdapter{Endpoint: "https://oss-cn-hangzhou.aliyuncs.com", AccessKeyID: "id", AccessKeySecret: "secret", SecurityToken: "token"}
+
+	form, err := adapter.PostPolicy(context.Background(), "bucket", "uploads/report.csv", PolicyOptions{
+		Expires:         time.Now().Add(time.Hour),
+		SuccessRedirect: "https://example.com/done",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if form.URL != "https://bucket.oss-cn-hangzhou.aliyuncs.com/" {
+		t.Errorf("unexpected url %q", form.URL)
+	}
+	if form.Fields["OSSAccessKeyId"] != "id" || form.Fields["x-oss-security-token"] != "token" || form.Fields["success_action_redirect"] != "https://example.com/done" {
+		t.Errorf("unexpected fields %v", form.Fields)
+	}
+
+	conditions := decodePolicy(t, form.Fields["policy"])
+	if !hasCondition(conditions, map[string]interface{}{"key": "uploads/report.csv"}) {
+		t.Errorf("the policy misses the key: %v", conditions)
+	}
+
+	mac := hmac.New(sha1.New, []byte("secret"))
+	mac.Write([]byte(form.Fields["policy"]))
+	if want := base64.StdEncoding.EncodeToString(mac.Sum(nil)); form.Fields["Signature"] != want {
+		t.Errorf("Signature = %q, want %q", form.Fields["Signature"], want)
+	}
+}
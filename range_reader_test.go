@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+var rangeTests = []struct {
+	offset, length int64
+	want           string
+}{
+	{0, -1, "0123456789"},
+	{2, 3, "234"},
+	{7, -1, "789"},
+	{8, 10, "89"},
+	{4, 0, ""},
+	{20, -1, ""},
+}
+
+func TestOpenRangeReaderRefusesNegativeOffset(t *testing.T) {
+	adapter := NewMemoryAdapter()
+	if _, err := adapter.Put(context.Background(), "bucket", "data", strings.NewReader("0123456789"), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := adapter.OpenRangeReader(context.Background(), "bucket", "data", -1, 2); err == nil {
+		t.Error("expected a negative offset to be refused by the adapter")
+	}
+	if _, err := New(adapter).OpenRangeReader("bucket", "data", -1, 2); err == nil {
+		t.Error("expected a negative offset to be refused by the builder")
+	}
+}
+
+func TestMemoryOpenRangeReader(t *testing.T) {
+	builder := New(NewMemoryAdapter())
+	if _, err := builder.UploadReader("bucket", "data", strings.NewReader("0123456789"), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range rangeTests {
+		rc, err := builder.OpenRangeReader("bucket", "data", tt.offset, tt.length)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := ioutil.ReadAll(rc)
+		rc.Close()
+		if string(data) != tt.want {
+			t.Errorf("range %d,%d = %q, want %q", tt.offset, tt.length, data, tt.want)
+		}
+	}
+
+	if _, err := builder.OpenReader("bucket", "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if _, err := builder.OpenRangeReader("bucket", "data", -1, 2); err == nil {
+		t.Error("expected a negative offset to be refused")
+	}
+}
+
+func TestLocalOpenRangeReader(t *testing.T) {
+	adapter, cleanup := newLocalTestAdapter(t)
+	defer cleanup()
+	builder := New(LocalClient{Root: adapter.Root})
+
+	if _, err := builder.UploadReader("bucket", "data", strings.NewReader("0123456789"), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range rangeTests {
+		rc, err := builder.OpenRangeReader("bucket", "data", tt.offset, tt.length)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := ioutil.ReadAll(rc)
+		rc.Close()
+		if string(data) != tt.want {
+			t.Errorf("range %d,%d = %q, want %q", tt.offset, tt.length, data, tt.want)
+		}
+	}
+
+	if _, err := builder.OpenReader("bucket", "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestOpenReaderStopsOnCancel(t *testing.T) {
+	adapter, cleanup := newLocalTestAdapter(t)
+	defer cleanup()
+
+	if _, err := adapter.UploadReader("bucket", "data", strings.NewReader("0123456789"), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	rc, err := adapter.OpenRangeReader(ctx, "bucket", "data", 0, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+
+	cancel()
+	if _, err := ioutil.ReadAll(rc); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
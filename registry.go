@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Config : The client given to New, the adapter is created by the factory
+// registered under its driver name
+type Config interface {
+	Driver() string
+}
+
+// Factory : Create the adapter from its config, which is the client given to New
+// as a value or a pointer. The config is nil when the adapter is created by
+// NewClient, the drivers which can't be configured from the environment return
+// ErrInvalidClient then, as they do for the config of another driver
+type Factory func(config Config) (Adapter, error)
+
+var (
+	driversMu sync.RWMutex
+	drivers   = make(map[string]Factory)
+)
+
+// Register : Make the adapter available to New and NewClient under name, which
+// is case insensitive. It panics when the factory is nil or the name is already
+// registered, so it is meant to be called from the init of the adapter package
+func Register(name string, factory Factory) {
+	driversMu.Lock()
+	defer driversMu.Unlock()
+
+	if factory == nil {
+		panic("storage: Register factory is nil")
+	}
+
+	name = strings.ToUpper(name)
+	if _, isExist := drivers[name]; isExist {
+		panic("storage: Register called twice for driver " + name)
+	}
+	drivers[name] = factory
+}
+
+// Drivers : The sorted names of the registered drivers
+func Drivers() []string {
+	driversMu.RLock()
+	defer driversMu.RUnlock()
+
+	names := make([]string, 0, len(drivers))
+	for name := range drivers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// invalidConfig : The error of a factory given a config it can't create the adapter from
+func invalidConfig(driver string, config Config) error {
+	return fmt.Errorf("%w: the %s driver can't be created from %T", ErrInvalidClient, driver, config)
+}
+
+func lookupDriver(name string) (Factory, error) {
+	driversMu.RLock()
+	factory, isExist := drivers[strings.ToUpper(name)]
+	driversMu.RUnlock()
+
+	if !isExist {
+		return nil, fmt.Errorf("%w: the client %s not supported, available drivers: %s",
+			ErrInvalidClient, name, strings.Join(Drivers(), ", "))
+	}
+
+	return factory, nil
+}
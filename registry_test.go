@@ -0,0 +1,141 @@
+package storage
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+)
+
+var (
+	registryTestOnce   sync.Once
+	registryTestMemory = NewMemoryAdapter()
+)
+
+// testClient : The config of a driver registered by the tests only
+type testClient struct {
+	Name string
+}
+
+func (client testClient) Driver() string {
+	return client.Name
+}
+
+func TestRegisterDriver(t *testing.T) {
+	memory := registryTestMemory
+	registryTestOnce.Do(func() {
+		Register("registry-test", func(config Config) (Adapter, error) {
+			if config == nil {
+				return nil, errors.New("storage: the test driver has no environment")
+			}
+			return memory, nil
+		})
+	})
+
+	found := false
+	for _, name := range Drivers() {
+		if name == "REGISTRY-TEST" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("the driver is not listed in %v", Drivers())
+	}
+
+	builder := New(testClient{Name: "Registry-Test"})
+	if _, err := builder.UploadReader("bucket", "report.csv", strings.NewReader("a,b"), ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, isExist := memory.Object("bucket", "report.csv"); !isExist {
+		t.Error("the adapter of the factory is not used")
+	}
+
+	if _, err := NewClient("registry-test").ReadFile("bucket", "report.csv"); err == nil {
+		t.Error("expected the factory error to be returned by the builder")
+	}
+}
+
+func TestRegisterPanics(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		factory Factory
+	}{
+		{"nil factory", nil},
+		{"duplicate", func(Config) (Adapter, error) { return NewMemoryAdapter(), nil }},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: expected Register to panic", tt.name)
+				}
+			}()
+			Register(strings.ToLower(MEMORY), tt.factory)
+		}()
+	}
+}
+
+func TestBuiltinDrivers(t *testing.T) {
+	names := strings.Join(Drivers(), ",")
+	for _, name := range []string{ALIYUN, AZURE, GCS, LOCAL, MEMORY, S3} {
+		if !strings.Contains(names, name) {
+			t.Errorf("the %s driver is not registered: %s", name, names)
+		}
+	}
+
+	memory := NewMemoryAdapter()
+	if builder := New(memory); builder.adapter != memory {
+		t.Error("expected New to share the memory adapter with the caller")
+	}
+	if builder := New(LocalClient{Root: "/data"}); builder.adapter.(*LocalAdapter).Root != "/data" {
+		t.Errorf("unexpected local adapter %+v", builder.adapter)
+	}
+}
+
+func TestUnknownClient(t *testing.T) {
+	for _, builder := range []*Builder{New(struct{}{}), New(testClient{Name: "missing"}), NewClient("missing")} {
+		err := builder.Close()
+		if !errors.Is(err, ErrInvalidClient) {
+			t.Errorf("expected ErrInvalidClient, got %v", err)
+			continue
+		}
+		if !strings.Contains(err.Error(), MEMORY) {
+			t.Errorf("expected the available drivers in %q", err.Error())
+		}
+	}
+}
+
+func TestPointerClients(t *testing.T) {
+	if builder := New(&LocalClient{Root: "/data"}); builder.err != nil || builder.adapter.(*LocalAdapter).Root != "/data" {
+		t.Errorf("unexpected local adapter %+v, %v", builder.adapter, builder.err)
+	}
+	if builder := New(&S3Client{Region: "us-east-1"}); builder.err != nil || builder.adapter.(*S3Adapter).Region != "us-east-1" {
+		t.Errorf("unexpected s3 adapter %+v, %v", builder.adapter, builder.err)
+	}
+	if builder := New(&MemoryClient{}); builder.err != nil {
+		t.Errorf("unexpected memory error %v", builder.err)
+	}
+
+	for _, client := range []interface{}{(*LocalClient)(nil), (*S3Client)(nil), (*AliyunClient)(nil), (*MemoryAdapter)(nil)} {
+		if err := New(client).Close(); !errors.Is(err, ErrInvalidClient) {
+			t.Errorf("%T: expected ErrInvalidClient, got %v", client, err)
+		}
+	}
+}
+
+func TestFactoriesRefuseOtherConfigs(t *testing.T) {
+	for _, name := range []string{ALIYUN, AZURE, GCS, LOCAL, MEMORY, S3} {
+		factory, err := lookupDriver(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := factory(testClient{Name: name}); !errors.Is(err, ErrInvalidClient) {
+			t.Errorf("%s: expected ErrInvalidClient, got %v", name, err)
+		}
+	}
+
+	for _, name := range []string{ALIYUN, LOCAL} {
+		if err := NewClient(name).Close(); !errors.Is(err, ErrInvalidClient) {
+			t.Errorf("%s: expected the environment to be refused, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,833 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	s3Scheme        = "s3://"
+	s3StorageHost   = ".amazonaws.com"
+	s3DefaultRegion = "us-east-1"
+	s3MetaPrefix    = "X-Amz-Meta-"
+)
+
+// S3Adapter : Talk to AWS or any S3 compatible server, e.g. MinIO. Endpoint defaults
+// to the AWS endpoint of the region, and PathStyle puts the bucket in the path
+// instead of the host, which most of the on-premise servers require
+type S3Adapter struct {
+	Endpoint        string
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	PathStyle       bool
+	HTTPClient      *http.Client
+}
+
+func init() {
+	Register(S3, func(config Config) (Adapter, error) {
+		var client S3Client
+		switch config := config.(type) {
+		case nil:
+			// the environment
+		case S3Client:
+			client = config
+		case *S3Client:
+			if config == nil {
+				return nil, invalidConfig(S3, config)
+			}
+			client = *config
+		default:
+			return nil, invalidConfig(S3, config)
+		}
+
+		adapter := new(S3Adapter)
+		adapter.Endpoint = client.Endpoint
+		adapter.Region = client.Region
+		adapter.AccessKeyID = client.AccessKeyID
+		adapter.SecretAccessKey = client.SecretAccessKey
+		adapter.SessionToken = client.SessionToken
+		adapter.PathStyle = client.PathStyle
+		adapter.HTTPClient = client.HTTPClient
+		if _, err := adapter.endpoint(); err != nil {
+			return nil, err
+		}
+		return adapter, nil
+	})
+}
+
+// S3ServiceError : The error document returned by the server, the head requests
+// have no body so only the status code is set
+type S3ServiceError struct {
+	StatusCode int
+	Code       string `xml:"Code"`
+	Message    string `xml:"Message"`
+	RequestID  string `xml:"RequestId"`
+}
+
+func (e *S3ServiceError) Error() string {
+	return fmt.Sprintf("s3: service returned error: StatusCode=%d, ErrorCode=%s, ErrorMessage=%q, RequestId=%s",
+		e.StatusCode, e.Code, e.Message, e.RequestID)
+}
+
+// UploadFile : Upload file to the bucket
+func (adapter *S3Adapter) UploadFile(file *multipart.FileHeader, bucket, filename string) (string, error) {
+	return adapter.UploadFileWithContext(context.Background(), file, bucket, filename)
+}
+
+// UploadFileWithContext : Upload file to the bucket
+func (adapter *S3Adapter) UploadFileWithContext(ctx context.Context, file *multipart.FileHeader, bucket, filename string) (string, error) {
+	result, err := uploadMultipartFile(ctx, adapter, file, bucket, filename)
+	if err != nil {
+		return "", err
+	}
+
+	return result.URL, nil
+}
+
+// DeleteFileUsingURL : Delete file from the bucket using url
+func (adapter *S3Adapter) DeleteFileUsingURL(bucket, fileURL string) error {
+	return adapter.DeleteFileUsingURLWithContext(context.Background(), bucket, fileURL)
+}
+
+// DeleteFileUsingURLWithContext : Delete file from the bucket using url
+func (adapter *S3Adapter) DeleteFileUsingURLWithContext(ctx context.Context, bucket, fileURL string) error {
+	key, err := adapter.objectKey(bucket, fileURL)
+	if err != nil {
+		return s3Error("delete", bucket, fileURL, err)
+	}
+
+	return adapter.DeleteObject(ctx, bucket, key)
+}
+
+// DeleteObject : Delete the object by its key, s3 doesn't fail when it doesn't exist
+func (adapter *S3Adapter) DeleteObject(ctx context.Context, bucket, key string) error {
+	resp, err := adapter.do(ctx, http.MethodDelete, bucket, key, nil, nil, nil)
+	if err != nil {
+		return s3Error("delete", bucket, key, err)
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+// DeleteMany : Delete the keys with DeleteObjects in quiet mode, in batches of
+// 1000, so only the failed keys are returned. A failed batch fails each of its keys
+func (adapter *S3Adapter) DeleteMany(ctx context.Context, bucket string, keys []string) error {
+	failures := &DeleteError{Bucket: bucket}
+	for _, batch := range deleteBatches(keys) {
+		if err := adapter.deleteBatch(ctx, bucket, batch, failures); err != nil {
+			for _, key := range batch {
+				failures.add(key, s3Error("delete", bucket, key, err))
+			}
+		}
+	}
+
+	return failures.errorOrNil()
+}
+
+// deleteBatch : Add the keys s3 failed to delete to the failures, the error is of
+// the whole request
+func (adapter *S3Adapter) deleteBatch(ctx context.Context, bucket string, keys []string, failures *DeleteError) error {
+	type s3ObjectIdentifier struct {
+		Key string
+	}
+
+	request := struct {
+		XMLName xml.Name `xml:"Delete"`
+		Quiet   bool
+		Objects []s3ObjectIdentifier `xml:"Object"`
+	}{Quiet: true}
+	for _, key := range keys {
+		request.Objects = append(request.Objects, s3ObjectIdentifier{Key: key})
+	}
+
+	body, err := xml.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	// the md5 is required by DeleteObjects
+	sum := md5.Sum(body)
+	header := make(http.Header)
+	header.Set("Content-MD5", base64.StdEncoding.EncodeToString(sum[:]))
+	header.Set("Content-Type", "application/xml")
+
+	resp, err := adapter.do(ctx, http.MethodPost, bucket, "", url.Values{"delete": {""}}, header, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Errors []struct {
+			Key     string
+			Code    string
+			Message string
+		} `xml:"Error"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return err
+	}
+
+	for _, e := range result.Errors {
+		failures.add(e.Key, s3Error("delete", bucket, e.Key, &S3ServiceError{StatusCode: resp.StatusCode, Code: e.Code, Message: e.Message}))
+	}
+
+	return nil
+}
+
+// Copy : Copy with CopyObject, up to 5GB. The overrides replace every header of
+// the object, so the others are carried over from the source
+func (adapter *S3Adapter) Copy(ctx context.Context, src, dst ObjectRef, options CopyOptions) (*UploadResult, error) {
+	header := make(http.Header)
+	if !options.isZero() {
+		resp, err := adapter.do(ctx, http.MethodHead, src.Bucket, src.Key, nil, nil, nil)
+		if err != nil {
+			return nil, s3Error("copy", src.Bucket, src.Key, err)
+		}
+		resp.Body.Close()
+
+		header = copyHeaders(resp.Header, s3MetaPrefix, options)
+		header.Set("X-Amz-Metadata-Directive", "REPLACE")
+	}
+
+	if err := adapter.copyObject(ctx, src, dst, header); err != nil {
+		return nil, s3Error("copy", src.Bucket, src.Key, err)
+	}
+
+	info, err := adapter.Stat(ctx, dst.Bucket, dst.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	return copyResult(adapter.uploadResult(dst.Bucket, dst.Key, info.Size, nil), info), nil
+}
+
+// UpdateMetadata : Replace the headers by copying the object onto itself, the
+// headers which don't change are carried over. The tags are put apart
+func (adapter *S3Adapter) UpdateMetadata(ctx context.Context, bucket, key string, attrs ObjectAttributes) error {
+	if attrs.hasHeaders() {
+		resp, err := adapter.do(ctx, http.MethodHead, bucket, key, nil, nil, nil)
+		if err != nil {
+			return s3Error("update", bucket, key, err)
+		}
+		resp.Body.Close()
+
+		header := replaceHeaders(resp.Header, s3MetaPrefix, "", attrs)
+		header.Set("X-Amz-Metadata-Directive", "REPLACE")
+		header.Set("X-Amz-Tagging-Directive", "COPY")
+
+		ref := ObjectRef{Provider: S3, Bucket: bucket, Key: key}
+		if err := adapter.copyObject(ctx, ref, ref, header); err != nil {
+			return s3Error("update", bucket, key, err)
+		}
+	}
+
+	if attrs.Tags != nil {
+		body, err := xml.Marshal(struct {
+			XMLName xml.Name `xml:"Tagging"`
+			tagSet
+		}{tagSet: newTagSet(attrs.Tags)})
+		if err != nil {
+			return s3Error("update", bucket, key, err)
+		}
+
+		// the md5 is required by PutObjectTagging
+		sum := md5.Sum(body)
+		header := make(http.Header)
+		header.Set("Content-MD5", base64.StdEncoding.EncodeToString(sum[:]))
+
+		resp, err := adapter.do(ctx, http.MethodPut, bucket, key, url.Values{"tagging": {""}}, header, body)
+		if err != nil {
+			return s3Error("update", bucket, key, err)
+		}
+		resp.Body.Close()
+	}
+
+	return nil
+}
+
+// copyObject : Send the CopyObject request with the headers of the copy
+func (adapter *S3Adapter) copyObject(ctx context.Context, src, dst ObjectRef, header http.Header) error {
+	header.Set("X-Amz-Copy-Source", s3EscapePath("/"+src.Bucket+"/"+src.Key))
+
+	resp, err := adapter.do(ctx, http.MethodPut, dst.Bucket, dst.Key, nil, header, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// the copy can still fail after the 200 status, in which case the body is an
+	// error document
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if bytes.Contains(data, []byte("<Error>")) {
+		e := &S3ServiceError{StatusCode: resp.StatusCode}
+		xml.Unmarshal(data, e)
+		return e
+	}
+
+	return nil
+}
+
+// TemporaryServingFile : Presign a GET url of the file, which expires at expiredDateTime
+func (adapter *S3Adapter) TemporaryServingFile(bucket, fileURL string, expiredDateTime time.Time, s3Client interface{}) (string, error) {
+	return adapter.TemporaryServingFileWithContext(context.Background(), bucket, fileURL, expiredDateTime, s3Client)
+}
+
+// TemporaryServingFileWithContext : Presign a GET url of the file, which expires at expiredDateTime
+func (adapter *S3Adapter) TemporaryServingFileWithContext(ctx context.Context, bucket, fileURL string, expiredDateTime time.Time, s3Client interface{}) (string, error) {
+	key, err := adapter.objectKey(bucket, fileURL)
+	if err != nil {
+		return "", s3Error("sign", bucket, fileURL, err)
+	}
+
+	return adapter.TemporaryServingObject(ctx, bucket, key, expiredDateTime, s3Client)
+}
+
+// TemporaryServingObject : Presign a GET url of the object with SigV4, the url is
+// signed locally so the context is only checked before signing. S3 caps the
+// expiry at 7 days
+func (adapter *S3Adapter) TemporaryServingObject(ctx context.Context, bucket, key string, expiredDateTime time.Time, s3Client interface{}) (string, error) {
+	return adapter.TemporaryServingObjectWithOptions(ctx, bucket, key, expiredDateTime, s3Client, ResponseOptions{})
+}
+
+// TemporaryServingObjectWithOptions : Presign a GET url of the object, with the
+// response overrides signed as response-* parameters
+func (adapter *S3Adapter) TemporaryServingObjectWithOptions(ctx context.Context, bucket, key string, expiredDateTime time.Time, s3Client interface{}, options ResponseOptions) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", s3Error("sign", bucket, key, err)
+	}
+
+	u, err := adapter.objectURL(bucket, key)
+	if err != nil {
+		return "", s3Error("sign", bucket, key, err)
+	}
+	u.RawQuery = options.query().Encode()
+
+	now := time.Now().UTC()
+	signedURL, err := adapter.signer().presign(http.MethodGet, u, nil, expiredDateTime.UTC().Sub(now), now)
+	if err != nil {
+		return "", s3Error("sign", bucket, key, err)
+	}
+
+	return signedURL, nil
+}
+
+// SignedUploadURL : Presign a PUT url of the object, the content type, md5 and
+// x-amz-* headers are signed. S3 can only enforce a max size with a post policy
+func (adapter *S3Adapter) SignedUploadURL(ctx context.Context, bucket, key string, options SignOptions) (*SignedRequest, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, s3Error("sign", bucket, key, err)
+	}
+
+	if options.MaxSize > 0 {
+		return nil, s3Error("sign", bucket, key, errMaxSizeNotSupported("s3"))
+	}
+
+	u, err := adapter.objectURL(bucket, key)
+	if err != nil {
+		return nil, s3Error("sign", bucket, key, err)
+	}
+
+	header := options.uploadHeaders()
+	now := time.Now().UTC()
+	signedURL, err := adapter.signer().presign(http.MethodPut, u, header, options.Expires.UTC().Sub(now), now)
+	if err != nil {
+		return nil, s3Error("sign", bucket, key, err)
+	}
+
+	return &SignedRequest{Method: http.MethodPut, URL: signedURL, Headers: header}, nil
+}
+
+// PostPolicy : Sign a SigV4 policy of a form upload, posted to the bucket url
+func (adapter *S3Adapter) PostPolicy(ctx context.Context, bucket, key string, options PolicyOptions) (*PostForm, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, s3Error("sign", bucket, key, err)
+	}
+
+	u, err := adapter.objectURL(bucket, "")
+	if err != nil {
+		return nil, s3Error("sign", bucket, key, err)
+	}
+
+	policy := newPostPolicy(bucket, key, options)
+	if err := adapter.signer().postPolicy(policy, time.Now()); err != nil {
+		return nil, s3Error("sign", bucket, key, err)
+	}
+
+	return &PostForm{URL: u.String(), Fields: policy.fields}, nil
+}
+
+// UploadReader :
+func (adapter *S3Adapter) UploadReader(bucket, filename string, reader io.Reader, contentType string) (string, error) {
+	return adapter.UploadReaderWithContext(context.Background(), bucket, filename, reader, contentType)
+}
+
+// UploadReaderWithContext :
+func (adapter *S3Adapter) UploadReaderWithContext(ctx context.Context, bucket, filename string, reader io.Reader, contentType string) (string, error) {
+	result, err := adapter.Put(ctx, bucket, filename, reader, contentType)
+	if err != nil {
+		return "", err
+	}
+
+	return result.URL, nil
+}
+
+// Put : Upload the reader and return the canonical identifiers of the object. The
+// reader is put in a single request when it fits in one part, and through a
+// multipart upload otherwise, whose etag is not the md5 of the object
+func (adapter *S3Adapter) Put(ctx context.Context, bucket, filename string, reader io.Reader, contentType string) (*UploadResult, error) {
+	return adapter.PutWithAttributes(ctx, bucket, filename, reader, contentType, ObjectAttributes{})
+}
+
+// PutWithAttributes : Same as Put, the metadata are set as x-amz-meta-* and the tags
+// with x-amz-tagging
+func (adapter *S3Adapter) PutWithAttributes(ctx context.Context, bucket, filename string, reader io.Reader, contentType string, attrs ObjectAttributes) (*UploadResult, error) {
+	return adapter.Upload(ctx, bucket, filename, reader, UploadOptions{ContentType: contentType, Attributes: attrs})
+}
+
+// Upload : The options are sent as headers. The md5 is checked before the object is
+// committed, since the parts of a multipart upload have no md5 of the whole object
+func (adapter *S3Adapter) Upload(ctx context.Context, bucket, filename string, reader io.Reader, options UploadOptions) (*UploadResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, s3Error("upload", bucket, filename, err)
+	}
+
+	var head []byte
+	if options.ContentType == "" {
+		var err error
+		if head, reader, err = peekContent(reader); err != nil {
+			msg := fmt.Errorf("Could not read file: %w", err)
+			return nil, s3Error("upload", bucket, filename, msg)
+		}
+	}
+
+	upload, err := adapter.newUpload(ctx, bucket, filename, options.ContentType, head)
+	if err != nil {
+		return nil, s3Error("upload", bucket, filename, err)
+	}
+	for key, values := range options.Attributes.header(s3MetaPrefix) {
+		upload.header[key] = values
+	}
+	if options.Attributes.Tags != nil {
+		upload.header.Set("X-Amz-Tagging", tagQuery(options.Attributes.Tags))
+	}
+	if options.ContentDisposition != "" {
+		upload.header.Set("Content-Disposition", options.ContentDisposition)
+	}
+	if options.ACL != "" {
+		upload.header.Set("X-Amz-Acl", options.ACL)
+	}
+	if options.StorageClass != "" {
+		upload.header.Set("X-Amz-Storage-Class", options.StorageClass)
+	}
+	if options.IfNotExists {
+		upload.conditions = http.Header{"If-None-Match": {"*"}}
+	}
+
+	hr := newHashReader(newContextReader(ctx, reader))
+	if err := upload.copy(hr); err != nil {
+		upload.abort()
+		msg := fmt.Errorf("Could not write file: %w", err)
+		return nil, s3Error("upload", bucket, filename, msg)
+	}
+
+	if err := hr.verify(options.MD5); err != nil {
+		upload.abort()
+		return nil, s3Error("upload", bucket, filename, err)
+	}
+
+	result, err := upload.commit()
+	if err != nil {
+		return nil, s3Error("upload", bucket, filename, err)
+	}
+	result.MD5 = hr.md5.Sum(nil)
+	result.CRC32C = hr.crc32c.Sum32()
+
+	return result, nil
+}
+
+// ReadFile :
+func (adapter *S3Adapter) ReadFile(bucket, path string) ([]byte, error) {
+	return adapter.ReadFileWithContext(context.Background(), bucket, path)
+}
+
+// ReadFileWithContext : Convenience wrapper over OpenReader, the whole object is kept in memory
+func (adapter *S3Adapter) ReadFileWithContext(ctx context.Context, bucket, path string) ([]byte, error) {
+	rc, err := adapter.OpenRangeReader(ctx, bucket, path, 0, -1)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	data, err := ioutil.ReadAll(rc)
+	if err != nil {
+		return nil, s3Error("read", bucket, path, err)
+	}
+
+	return data, nil
+}
+
+// OpenRangeReader : Read length bytes from offset, a negative length reads until the end of the object
+func (adapter *S3Adapter) OpenRangeReader(ctx context.Context, bucket, path string, offset, length int64) (io.ReadCloser, error) {
+	header := make(http.Header)
+	if length == 0 {
+		return ioutil.NopCloser(bytes.NewReader(nil)), nil
+	} else if length > 0 {
+		header.Set("Range", fmt.Sprintf("bytes=%d-%d", offset, offset+length-1))
+	} else if offset > 0 {
+		header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := adapter.do(ctx, http.MethodGet, bucket, path, nil, header, nil)
+	if err != nil {
+		// reading past the end of the object returns nothing, same as the other adapters
+		if e, ok := err.(*S3ServiceError); ok && e.Code == "InvalidRange" {
+			return ioutil.NopCloser(bytes.NewReader(nil)), nil
+		}
+		return nil, s3Error("read", bucket, path, err)
+	}
+
+	return resp.Body, nil
+}
+
+// UploadBuffer :
+func (adapter *S3Adapter) UploadBuffer(bucket, filename string, contentType string) (*Buffer, error) {
+	return adapter.UploadBufferWithContext(context.Background(), bucket, filename, contentType)
+}
+
+// UploadBufferWithContext : The data is buffered until a part is full and sent
+// through a multipart upload, which is completed by Close
+func (adapter *S3Adapter) UploadBufferWithContext(ctx context.Context, bucket, filename string, contentType string) (*Buffer, error) {
+	buf := new(Buffer)
+	buf.adapter = S3
+	buf.ctx = ctx
+
+	if err := ctx.Err(); err != nil {
+		return nil, s3Error("upload", bucket, filename, err)
+	}
+
+	upload, err := adapter.newUpload(ctx, bucket, filename, contentType, nil)
+	if err != nil {
+		return nil, s3Error("upload", bucket, filename, err)
+	}
+
+	buf.s3 = upload
+	buf.filename = filename
+	buf.bucket = bucket
+	return buf, nil
+}
+
+// List : ListObjectsV2, the continuation token is used as the page token
+func (adapter *S3Adapter) List(ctx context.Context, bucket string, options ListOptions) (*ListResult, error) {
+	pageSize := options.PageSize
+	if pageSize <= 0 {
+		pageSize = defaultListPageSize
+	}
+
+	query := make(url.Values)
+	query.Set("list-type", "2")
+	query.Set("max-keys", strconv.Itoa(pageSize))
+	if options.Prefix != "" {
+		query.Set("prefix", options.Prefix)
+	}
+	if options.Delimiter != "" {
+		query.Set("delimiter", options.Delimiter)
+	}
+	if options.PageToken != "" {
+		query.Set("continuation-token", options.PageToken)
+	}
+
+	resp, err := adapter.do(ctx, http.MethodGet, bucket, "", query, nil, nil)
+	if err != nil {
+		return nil, s3Error("list", bucket, options.Prefix, err)
+	}
+	defer resp.Body.Close()
+
+	var objects struct {
+		IsTruncated           bool
+		NextContinuationToken string
+		Contents              []struct {
+			Key          string
+			Size         int64
+			ETag         string
+			LastModified time.Time
+		}
+		CommonPrefixes []struct {
+			Prefix string
+		}
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&objects); err != nil {
+		return nil, s3Error("list", bucket, options.Prefix, err)
+	}
+
+	result := new(ListResult)
+	if objects.IsTruncated {
+		result.NextPageToken = objects.NextContinuationToken
+	}
+	for _, o := range objects.Contents {
+		result.Objects = append(result.Objects, &ObjectInfo{
+			Name:    o.Key,
+			Size:    o.Size,
+			ETag:    strings.Trim(o.ETag, `"`),
+			Updated: o.LastModified,
+		})
+	}
+	for _, prefix := range objects.CommonPrefixes {
+		result.Objects = append(result.Objects, &ObjectInfo{Prefix: prefix.Prefix})
+	}
+
+	return result, nil
+}
+
+// Stat : Return the object attributes through a HEAD request. S3 has no creation
+// time nor crc32c, and the etag is only the md5 when the object was put in one part
+func (adapter *S3Adapter) Stat(ctx context.Context, bucket, filename string) (*ObjectInfo, error) {
+	resp, err := adapter.do(ctx, http.MethodHead, bucket, filename, nil, nil, nil)
+	if err != nil {
+		return nil, s3Error("stat", bucket, filename, err)
+	}
+	resp.Body.Close()
+
+	header := resp.Header
+	info := new(ObjectInfo)
+	info.Name = filename
+	info.Size, _ = strconv.ParseInt(header.Get("Content-Length"), 10, 64)
+	info.ContentType = header.Get("Content-Type")
+	info.ContentDisposition = header.Get("Content-Disposition")
+	info.ContentEncoding = header.Get("Content-Encoding")
+	info.ContentLanguage = header.Get("Content-Language")
+	info.CacheControl = header.Get("Cache-Control")
+	info.ETag = strings.Trim(header.Get("ETag"), `"`)
+	if md5, err := hex.DecodeString(info.ETag); err == nil && len(md5) == 16 {
+		info.MD5 = md5
+	}
+	info.Updated, _ = http.ParseTime(header.Get("Last-Modified"))
+	info.Metadata = make(map[string]string)
+	for key := range header {
+		if strings.HasPrefix(key, s3MetaPrefix) {
+			info.Metadata[strings.ToLower(key[len(s3MetaPrefix):])] = header.Get(key)
+		}
+	}
+
+	return info, nil
+}
+
+// Close : The adapter holds no connection of its own, the idle ones of the http
+// client are closed
+func (adapter *S3Adapter) Close() error {
+	if adapter.HTTPClient != nil {
+		adapter.HTTPClient.CloseIdleConnections()
+	}
+	return nil
+}
+
+func (adapter *S3Adapter) uploadResult(bucket, filename string, size int64, header http.Header) *UploadResult {
+	contentType, _, _ := resolveContentType(filename, "", nil)
+	if ct := header.Get("Content-Type"); ct != "" {
+		contentType = ct
+	}
+
+	fileURL := ""
+	if u, err := adapter.objectURL(bucket, filename); err == nil {
+		fileURL = u.String()
+	}
+
+	return &UploadResult{
+		Bucket:      bucket,
+		Key:         filename,
+		URL:         fileURL,
+		Size:        size,
+		ContentType: contentType,
+	}
+}
+
+func (adapter *S3Adapter) signer() *s3Signer {
+	region := adapter.Region
+	if region == "" {
+		region = s3DefaultRegion
+	}
+
+	return &s3Signer{
+		region:          region,
+		accessKeyID:     adapter.AccessKeyID,
+		secretAccessKey: adapter.SecretAccessKey,
+		sessionToken:    adapter.SessionToken,
+	}
+}
+
+func (adapter *S3Adapter) httpClient() *http.Client {
+	if adapter.HTTPClient != nil {
+		return adapter.HTTPClient
+	}
+	return http.DefaultClient
+}
+
+func (adapter *S3Adapter) endpoint() (*url.URL, error) {
+	endpoint := adapter.Endpoint
+	if endpoint == "" {
+		region := adapter.Region
+		if region == "" {
+			region = s3DefaultRegion
+		}
+		endpoint = fmt.Sprintf("https://s3.%s%s", region, s3StorageHost)
+	} else if !strings.Contains(endpoint, "://") {
+		endpoint = "https://" + endpoint
+	}
+
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid s3 endpoint %q: %v", ErrInvalidClient, adapter.Endpoint, err)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("%w: invalid s3 endpoint %q", ErrInvalidClient, adapter.Endpoint)
+	}
+
+	return u, nil
+}
+
+// objectURL : The url of the object, or of the bucket when the key is empty
+func (adapter *S3Adapter) objectURL(bucket, key string) (*url.URL, error) {
+	if bucket == "" {
+		return nil, fmt.Errorf("storage: bucket is required")
+	}
+
+	u, err := adapter.endpoint()
+	if err != nil {
+		return nil, err
+	}
+
+	path := strings.TrimSuffix(u.Path, "/")
+	if adapter.PathStyle {
+		path += "/" + bucket
+		if key != "" {
+			path += "/" + key
+		}
+	} else {
+		u.Host = bucket + "." + u.Host
+		path += "/" + key
+	}
+
+	u.Path = path
+	u.RawPath = s3EscapePath(path)
+	u.RawQuery = ""
+	return u, nil
+}
+
+// objectKey : Strip the endpoint of the adapter from the url, so the urls of a
+// custom endpoint are understood too
+func (adapter *S3Adapter) objectKey(bucket, fileURL string) (string, error) {
+	if !strings.Contains(fileURL, "://") {
+		return fileURL, nil
+	}
+
+	base, err := adapter.objectURL(bucket, "")
+	if err != nil {
+		return "", err
+	}
+
+	u, err := url.Parse(fileURL)
+	if err == nil && strings.EqualFold(u.Host, base.Host) {
+		prefix := strings.TrimSuffix(base.Path, "/") + "/"
+		if strings.HasPrefix(u.Path, prefix) && len(u.Path) > len(prefix) {
+			return strings.TrimPrefix(u.Path, prefix), nil
+		}
+	}
+
+	return objectKeyFromURL(bucket, fileURL)
+}
+
+// do : Sign and send the request, the response is returned only when it succeeded
+// and its body must be closed by the caller
+func (adapter *S3Adapter) do(ctx context.Context, method, bucket, key string, query url.Values, header http.Header, body []byte) (*http.Response, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	u, err := adapter.objectURL(bucket, key)
+	if err != nil {
+		return nil, err
+	}
+	if query != nil {
+		u.RawQuery = s3CanonicalQuery(query)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.ContentLength = int64(len(body))
+	if len(body) == 0 {
+		req.Body = http.NoBody
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+
+	payloadHash := s3EmptyPayload
+	if len(body) > 0 {
+		sum := sha256.Sum256(body)
+		payloadHash = hex.EncodeToString(sum[:])
+	}
+	adapter.signer().sign(req, payloadHash, time.Now())
+
+	resp, err := adapter.httpClient().Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		defer resp.Body.Close()
+		return nil, readS3ServiceError(resp)
+	}
+
+	return resp, nil
+}
+
+func readS3ServiceError(resp *http.Response) error {
+	e := &S3ServiceError{StatusCode: resp.StatusCode}
+	if data, err := ioutil.ReadAll(resp.Body); err == nil && len(data) > 0 {
+		xml.Unmarshal(data, e)
+	}
+	if e.RequestID == "" {
+		e.RequestID = resp.Header.Get("X-Amz-Request-Id")
+	}
+
+	return e
+}
+
+// s3BucketPattern : 3 to 63 lowercase letters, digits, dots and dashes, starting and
+// ending with a letter or digit
+var s3BucketPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]{1,61}[a-z0-9]$`)
+
+// validateBucket : The names can't have two dots in a row, be an ip address or
+// start with the "xn--" prefix s3 reserves
+func (adapter *S3Adapter) validateBucket(bucket string) error {
+	if !s3BucketPattern.MatchString(bucket) || strings.Contains(bucket, "..") ||
+		net.ParseIP(bucket) != nil || strings.HasPrefix(bucket, "xn--") {
+		return errInvalidBucketName(bucket)
+	}
+
+	return nil
+}
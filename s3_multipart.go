@@ -0,0 +1,208 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// s3PartSize : The smallest part s3 accepts, but for the last one
+const s3PartSize = 5 << 20
+
+// s3Upload : Buffer the data until a part is full. The object is put in a single
+// request when it fits in one part, and through a multipart upload otherwise. The
+// conditions are only sent with the request making the object visible
+type s3Upload struct {
+	ctx        context.Context
+	adapter    *S3Adapter
+	bucket     string
+	key        string
+	header     http.Header
+	conditions http.Header
+	uploadID   string
+	parts      []s3CompletedPart
+	pending    bytes.Buffer
+	size       int64
+}
+
+type s3CompletedPart struct {
+	PartNumber int
+	ETag       string
+}
+
+func (adapter *S3Adapter) newUpload(ctx context.Context, bucket, key, contentType string, head []byte) (*s3Upload, error) {
+	ct, disposition, err := resolveContentType(key, contentType, head)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make(http.Header)
+	if ct != "" {
+		header.Set("Content-Type", ct)
+	}
+	if disposition != "" {
+		header.Set("Content-Disposition", disposition)
+	}
+
+	return &s3Upload{
+		ctx:     ctx,
+		adapter: adapter,
+		bucket:  bucket,
+		key:     key,
+		header:  header,
+	}, nil
+}
+
+// copy : Send a part every time the pending data fills one
+func (upload *s3Upload) copy(reader io.Reader) error {
+	for {
+		n, err := io.CopyN(&upload.pending, reader, int64(s3PartSize-upload.pending.Len()))
+		upload.size += n
+		if upload.pending.Len() >= s3PartSize {
+			if err := upload.uploadPart(); err != nil {
+				return err
+			}
+		}
+
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+func (upload *s3Upload) uploadPart() error {
+	if upload.uploadID == "" {
+		if err := upload.initiate(); err != nil {
+			return err
+		}
+	}
+
+	partNumber := len(upload.parts) + 1
+	query := make(url.Values)
+	query.Set("partNumber", strconv.Itoa(partNumber))
+	query.Set("uploadId", upload.uploadID)
+
+	resp, err := upload.adapter.do(upload.ctx, http.MethodPut, upload.bucket, upload.key, query, nil, upload.pending.Bytes())
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	upload.parts = append(upload.parts, s3CompletedPart{PartNumber: partNumber, ETag: resp.Header.Get("ETag")})
+	upload.pending.Reset()
+	return nil
+}
+
+func (upload *s3Upload) initiate() error {
+	query := url.Values{"uploads": {""}}
+	resp, err := upload.adapter.do(upload.ctx, http.MethodPost, upload.bucket, upload.key, query, upload.header, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		UploadID string `xml:"UploadId"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return err
+	}
+	upload.uploadID = result.UploadID
+	return nil
+}
+
+// commit : Put the pending data, or complete the multipart upload. The upload is
+// aborted when it fails, so the parts are not left behind
+func (upload *s3Upload) commit() (*UploadResult, error) {
+	if upload.uploadID == "" {
+		header := make(http.Header)
+		for k, v := range upload.header {
+			header[k] = v
+		}
+		for k, v := range upload.conditions {
+			header[k] = v
+		}
+
+		resp, err := upload.adapter.do(upload.ctx, http.MethodPut, upload.bucket, upload.key, nil, header, upload.pending.Bytes())
+		if err != nil {
+			return nil, err
+		}
+		resp.Body.Close()
+
+		return upload.result(resp.Header.Get("ETag"), resp.Header), nil
+	}
+
+	if upload.pending.Len() > 0 {
+		if err := upload.uploadPart(); err != nil {
+			upload.abort()
+			return nil, err
+		}
+	}
+
+	body, err := xml.Marshal(struct {
+		XMLName xml.Name          `xml:"CompleteMultipartUpload"`
+		Parts   []s3CompletedPart `xml:"Part"`
+	}{Parts: upload.parts})
+	if err != nil {
+		upload.abort()
+		return nil, err
+	}
+
+	query := url.Values{"uploadId": {upload.uploadID}}
+	resp, err := upload.adapter.do(upload.ctx, http.MethodPost, upload.bucket, upload.key, query, upload.conditions, body)
+	if err != nil {
+		upload.abort()
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// the completion can still fail after the 200 status, in which case the body
+	// is an error document
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		upload.abort()
+		return nil, err
+	}
+	if bytes.Contains(data, []byte("<Error>")) {
+		e := &S3ServiceError{StatusCode: resp.StatusCode}
+		xml.Unmarshal(data, e)
+		upload.abort()
+		return nil, e
+	}
+
+	var result struct {
+		ETag string
+	}
+	xml.Unmarshal(data, &result)
+
+	return upload.result(result.ETag, resp.Header), nil
+}
+
+// abort : Discard the uploaded parts, with a context of its own since the upload
+// context is usually the reason of the failure
+func (upload *s3Upload) abort() {
+	if upload.uploadID == "" {
+		return
+	}
+
+	query := url.Values{"uploadId": {upload.uploadID}}
+	if resp, err := upload.adapter.do(context.Background(), http.MethodDelete, upload.bucket, upload.key, query, nil, nil); err == nil {
+		resp.Body.Close()
+	}
+	upload.uploadID = ""
+}
+
+func (upload *s3Upload) result(etag string, header http.Header) *UploadResult {
+	result := upload.adapter.uploadResult(upload.bucket, upload.key, upload.size, upload.header)
+	result.ETag = strings.Trim(etag, `"`)
+	result.VersionID = header.Get("X-Amz-Version-Id")
+	return result
+}
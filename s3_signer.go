@@ -0,0 +1,217 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	s3Algorithm       = "AWS4-HMAC-SHA256"
+	s3Service         = "s3"
+	s3UnsignedPayload = "UNSIGNED-PAYLOAD"
+	s3EmptyPayload    = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	s3DateFormat      = "20060102T150405Z"
+	s3MaxPresignAge   = 7 * 24 * time.Hour
+)
+
+// s3Signer : AWS signature version 4, see
+// https://docs.aws.amazon.com/AmazonS3/latest/API/sig-v4-authenticating-requests.html
+type s3Signer struct {
+	region          string
+	accessKeyID     string
+	secretAccessKey string
+	sessionToken    string
+}
+
+// sign : Sign the request in the Authorization header. The payload hash is
+// s3UnsignedPayload for the streamed bodies
+func (signer *s3Signer) sign(req *http.Request, payloadHash string, now time.Time) {
+	now = now.UTC()
+	req.Header.Set("X-Amz-Date", now.Format(s3DateFormat))
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if signer.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", signer.sessionToken)
+	}
+
+	headers, signedHeaders := signer.canonicalHeaders(req.URL.Host, req.Header)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		s3EscapePath(req.URL.Path),
+		s3CanonicalQuery(req.URL.Query()),
+		headers,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := signer.scope(now)
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s3Algorithm, signer.accessKeyID, scope, signedHeaders, signer.signature(now, scope, canonicalRequest)))
+}
+
+// presign : Sign the url in the query string, the headers are signed too so the
+// client must send them as they are
+func (signer *s3Signer) presign(method string, u *url.URL, header http.Header, expires time.Duration, now time.Time) (string, error) {
+	if expires <= 0 || expires > s3MaxPresignAge {
+		return "", fmt.Errorf("storage: s3 signed url must expire within %s", s3MaxPresignAge)
+	}
+
+	now = now.UTC()
+	scope := signer.scope(now)
+	_, signedHeaders := signer.canonicalHeaders(u.Host, header)
+
+	query := u.Query()
+	query.Set("X-Amz-Algorithm", s3Algorithm)
+	query.Set("X-Amz-Credential", signer.accessKeyID+"/"+scope)
+	query.Set("X-Amz-Date", now.Format(s3DateFormat))
+	query.Set("X-Amz-Expires", fmt.Sprintf("%d", int64(expires/time.Second)))
+	query.Set("X-Amz-SignedHeaders", signedHeaders)
+	if signer.sessionToken != "" {
+		query.Set("X-Amz-Security-Token", signer.sessionToken)
+	}
+
+	headers, _ := signer.canonicalHeaders(u.Host, header)
+	canonicalRequest := strings.Join([]string{
+		method,
+		s3EscapePath(u.Path),
+		s3CanonicalQuery(query),
+		headers,
+		signedHeaders,
+		s3UnsignedPayload,
+	}, "\n")
+
+	query.Set("X-Amz-Signature", signer.signature(now, scope, canonicalRequest))
+
+	signed := *u
+	signed.RawQuery = s3CanonicalQuery(query)
+	return signed.String(), nil
+}
+
+func (signer *s3Signer) scope(now time.Time) string {
+	return fmt.Sprintf("%s/%s/%s/aws4_request", now.Format("20060102"), signer.region, s3Service)
+}
+
+func (signer *s3Signer) signature(now time.Time, scope, canonicalRequest string) string {
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		s3Algorithm,
+		now.Format(s3DateFormat),
+		scope,
+		hex.EncodeToString(hash[:]),
+	}, "\n")
+
+	return hex.EncodeToString(hmacSHA256(signer.signingKey(now), stringToSign))
+}
+
+// signingKey : The key of the scope, derived from the secret
+func (signer *s3Signer) signingKey(now time.Time) []byte {
+	key := hmacSHA256([]byte("AWS4"+signer.secretAccessKey), now.Format("20060102"))
+	key = hmacSHA256(key, signer.region)
+	key = hmacSHA256(key, s3Service)
+	return hmacSHA256(key, "aws4_request")
+}
+
+// postPolicy : Sign the policy document of a form upload, see
+// https://docs.aws.amazon.com/AmazonS3/latest/API/sigv4-HTTPPOSTConstructPolicy.html
+func (signer *s3Signer) postPolicy(policy *postPolicy, now time.Time) error {
+	now = now.UTC()
+	if expires := policy.options.Expires.Sub(now); expires <= 0 || expires > s3MaxPresignAge {
+		return fmt.Errorf("storage: s3 post policy must expire within %s", s3MaxPresignAge)
+	}
+
+	policy.set("x-amz-algorithm", s3Algorithm)
+	policy.set("x-amz-credential", signer.accessKeyID+"/"+signer.scope(now))
+	policy.set("x-amz-date", now.Format(s3DateFormat))
+	if signer.sessionToken != "" {
+		policy.set("x-amz-security-token", signer.sessionToken)
+	}
+
+	document, err := policy.encode("2006-01-02T15:04:05.000Z")
+	if err != nil {
+		return err
+	}
+	policy.fields["policy"] = document
+	policy.fields["x-amz-signature"] = hex.EncodeToString(hmacSHA256(signer.signingKey(now), document))
+
+	return nil
+}
+
+// canonicalHeaders : The host, every x-amz-* header and the content headers are signed
+func (signer *s3Signer) canonicalHeaders(host string, header http.Header) (string, string) {
+	values := map[string]string{"host": host}
+	for key := range header {
+		name := strings.ToLower(key)
+		if strings.HasPrefix(name, "x-amz-") || name == "content-type" || name == "content-md5" {
+			values[name] = strings.TrimSpace(strings.Join(header[key], ","))
+		}
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonical strings.Builder
+	for _, name := range names {
+		canonical.WriteString(name + ":" + values[name] + "\n")
+	}
+
+	return canonical.String(), strings.Join(names, ";")
+}
+
+func s3CanonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		values := append([]string(nil), query[key]...)
+		sort.Strings(values)
+		for _, value := range values {
+			pairs = append(pairs, s3Escape(key, true)+"="+s3Escape(value, true))
+		}
+	}
+
+	return strings.Join(pairs, "&")
+}
+
+func s3EscapePath(path string) string {
+	if path == "" {
+		return "/"
+	}
+	return s3Escape(path, false)
+}
+
+// s3Escape : Escape everything but the unreserved characters, the slash is kept
+// in the paths
+func s3Escape(value string, escapeSlash bool) string {
+	var escaped strings.Builder
+	for _, b := range []byte(value) {
+		switch {
+		case 'A' <= b && b <= 'Z', 'a' <= b && b <= 'z', '0' <= b && b <= '9',
+			b == '-', b == '_', b == '.', b == '~':
+			escaped.WriteByte(b)
+		case b == '/' && !escapeSlash:
+			escaped.WriteByte(b)
+		default:
+			fmt.Fprintf(&escaped, "%%%02X", b)
+		}
+	}
+	return escaped.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
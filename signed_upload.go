@@ -0,0 +1,98 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// SignOptions : The conditions of a signed upload url. ContentType and ContentMD5
+// (base64) are signed, so the client must send the same values. MaxSize is only
+// enforced by GCS, the other adapters refuse to sign it rather than ignore it
+type SignOptions struct {
+	Expires     time.Time
+	ContentType string
+	ContentMD5  string
+	MaxSize     int64
+	Headers     map[string]string
+}
+
+// SignedRequest : The request the client must send, with every header as it is
+type SignedRequest struct {
+	Method  string
+	URL     string
+	Headers http.Header
+}
+
+// UploadURLSigner : Implemented by the adapters which can sign upload urls
+type UploadURLSigner interface {
+	SignedUploadURL(ctx context.Context, bucket, key string, options SignOptions) (*SignedRequest, error)
+}
+
+var _ UploadURLSigner = &GCSAdapter{}
+var _ UploadURLSigner = &AliyunAdapter{}
+var _ UploadURLSigner = &S3Adapter{}
+var _ UploadURLSigner = &AzureBlobAdapter{}
+
+// SignedUploadURL : Sign a PUT url, so the client uploads straight to the bucket
+func (b *Builder) SignedUploadURL(bucket, key string, options SignOptions) (*SignedRequest, error) {
+	return b.SignedUploadURLWithContext(context.Background(), bucket, key, options)
+}
+
+// SignedUploadURLWithContext :
+func (b *Builder) SignedUploadURLWithContext(ctx context.Context, bucket, key string, options SignOptions) (*SignedRequest, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	var (
+		errKeyIsRequired    = errors.New("storage: key is required")
+		errBucketIsRequired = errors.New("storage: bucket is required")
+		errInvalidExpires   = errors.New("storage: expires must be in the future")
+		errInvalidMaxSize   = errors.New("storage: max size must not be negative")
+	)
+
+	if len(key) == 0 {
+		return nil, errKeyIsRequired
+	}
+
+	if len(bucket) == 0 {
+		return nil, errBucketIsRequired
+	}
+
+	if !options.Expires.After(time.Now()) {
+		return nil, errInvalidExpires
+	}
+
+	if options.MaxSize < 0 {
+		return nil, errInvalidMaxSize
+	}
+
+	signer, ok := b.adapter.(UploadURLSigner)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T can't sign upload urls", ErrUnsupported, b.adapter)
+	}
+
+	return signer.SignedUploadURL(ctx, bucket, key, options)
+}
+
+// uploadHeaders : The headers of the options, which the client must send
+func (options SignOptions) uploadHeaders() http.Header {
+	header := make(http.Header)
+	for key, value := range options.Headers {
+		header.Set(key, value)
+	}
+	if options.ContentType != "" {
+		header.Set("Content-Type", options.ContentType)
+	}
+	if options.ContentMD5 != "" {
+		header.Set("Content-MD5", options.ContentMD5)
+	}
+
+	return header
+}
+
+func errMaxSizeNotSupported(provider string) error {
+	return fmt.Errorf("%w: %s can't enforce the max size of a signed upload url", ErrUnsupported, provider)
+}
@@ -0,0 +1,62 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// ResponseOptions : The headers the provider serves a signed url with, in place of
+// the ones stored with the object. The overrides are signed, so they can't be
+// changed by whoever holds the url
+type ResponseOptions struct {
+	ContentDisposition string
+	ContentType        string
+	CacheControl       string
+}
+
+// ResponseOverrideSigner : Implemented by the adapters which can sign response overrides
+type ResponseOverrideSigner interface {
+	TemporaryServingObjectWithOptions(ctx context.Context, bucket, key string, expiredTime time.Time, client interface{}, options ResponseOptions) (string, error)
+}
+
+var _ ResponseOverrideSigner = &GCSAdapter{}
+var _ ResponseOverrideSigner = &AliyunAdapter{}
+var _ ResponseOverrideSigner = &S3Adapter{}
+var _ ResponseOverrideSigner = &AzureBlobAdapter{}
+
+// TemporaryServingObjectWithOptions : Same as TemporaryServingObject, served with
+// the response headers of the options, e.g. to download an invoice as an attachment
+func (b *Builder) TemporaryServingObjectWithOptions(ctx context.Context, bucket, key string, expiredTime time.Time, client interface{}, options ResponseOptions) (string, error) {
+	if b.err != nil {
+		return "", b.err
+	}
+
+	if options == (ResponseOptions{}) {
+		return b.adapter.TemporaryServingObject(ctx, bucket, key, expiredTime, client)
+	}
+
+	signer, ok := b.adapter.(ResponseOverrideSigner)
+	if !ok {
+		return "", fmt.Errorf("%w: %T can't sign response overrides", ErrUnsupported, b.adapter)
+	}
+
+	return signer.TemporaryServingObjectWithOptions(ctx, bucket, key, expiredTime, client, options)
+}
+
+// query : The response-* parameters shared by gcs, aliyun and s3
+func (options ResponseOptions) query() url.Values {
+	query := make(url.Values)
+	if options.ContentDisposition != "" {
+		query.Set("response-content-disposition", options.ContentDisposition)
+	}
+	if options.ContentType != "" {
+		query.Set("response-content-type", options.ContentType)
+	}
+	if options.CacheControl != "" {
+		query.Set("response-cache-control", options.CacheControl)
+	}
+
+	return query
+}
@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+var attachment = ResponseOptions{ContentType: "application/octet-stream", ContentDisposition: `attachment; filename="report.pdf"`}
+
+func TestResponseOverridesAreSigned(t *testing.T) {
+	ctx := context.Background()
+	expires := time.Now().Add(time.Hour)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	privateKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	tests := []struct {
+		name      string
+		signer    ResponseOverrideSigner
+		signature string
+	}{
+		{"gcs", &GCSAdapter{Credential: &GoogleClient{ClientEmail: "signer@project.iam.gserviceaccount.com", PrivateKey: string(privateKey)}}, "X-Goog-Signature"},
+		{"s3", &S3Adapter{Region: "us-east-1", AccessKeyID: "AKID", SecretAccessKey: "secret"}, "X-Amz-Signature"},
+		{"aliyun", &AliyunAdapter{Endpoint: "oss-cn-hangzhou.aliyuncs.com", AccessKeyID: "id", AccessKeySecret: "secret"}, "Signature"},
+	}
+
+	for _, tt := range tests {
+		signedURL, err := tt.signer.TemporaryServingObjectWithOptions(ctx, "bucket", "report.pdf", expires, nil, attachment)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		u, err := url.Parse(signedURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		query := u.Query()
+		if query.Get("response-content-disposition") != attachment.ContentDisposition || query.Get("response-content-type") != attachment.ContentType {
+			t.Errorf("%s: the overrides are not in %q", tt.name, signedURL)
+		}
+		if query.Get(tt.signature) == "" {
+			t.Errorf("%s: %q is not signed", tt.name, signedURL)
+		}
+	}
+}
+
+func TestGCSRefusesCacheControlOverride(t *testing.T) {
+	adapter := &GCSAdapter{Credential: &GoogleClient{ClientEmail: "signer@project.iam.gserviceaccount.com"}}
+	_, err := adapter.TemporaryServingObjectWithOptions(context.Background(), "bucket", "report.pdf", time.Now().Add(time.Hour), nil,
+		ResponseOptions{CacheControl: "no-cache"})
+	if !errors.Is(err, ErrUnsupported) {
+		t.Errorf("expected ErrUnsupported, got %v", err)
+	}
+}
+
+func TestBuilderResponseOverrides(t *testing.T) {
+	ctx := context.Background()
+	builder := New(NewMemoryAdapter())
+	if _, err := builder.UploadReader("bucket", "report.pdf", strings.NewReader("%PDF-1.4"), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := builder.TemporaryServingObjectWithOptions(ctx, "bucket", "report.pdf", time.Now().Add(time.Hour), nil, ResponseOptions{}); err != nil {
+		t.Errorf("expected the url without overrides, got %v", err)
+	}
+	if _, err := builder.TemporaryServingObjectWithOptions(ctx, "bucket", "report.pdf", time.Now().Add(time.Hour), nil, attachment); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("expected ErrUnsupported, got %v", err)
+	}
+}
+
+func TestAzureResponseOverrides(t *testing.T) {
+	ctx := context.Background()
+	fake, adapter, stop := newFakeAzure(t)
+	defer stop()
+	fake.blobs["/container/report.pdf"] = []byte("%PDF-1.4")
+
+	signedURL, err := adapter.TemporaryServingObjectWithOptions(ctx, "container", "report.pdf", time.Now().Add(time.Hour), nil, attachment)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := http.Get(signedURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected the signed url to be accepted, got %s", resp.Status)
+	}
+	if resp.Header.Get("Content-Type") != "application/octet-stream" {
+		t.Errorf("expected the content type override, got %q", resp.Header.Get("Content-Type"))
+	}
+
+	// a tampered override must break the signature
+	resp, err = http.Get(strings.Replace(signedURL, "octet-stream", "pdf", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected a tampered url to be refused, got %s", resp.Status)
+	}
+}
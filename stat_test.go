@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestMemoryStat(t *testing.T) {
+	adapter := NewMemoryAdapter()
+	builder := New(adapter)
+	if _, err := builder.UploadReader("bucket", "report.pdf", strings.NewReader("%PDF-1.4"), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := builder.Stat("bucket", "report.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := md5.Sum([]byte("%PDF-1.4"))
+	if info.Name != "report.pdf" || info.Size != 8 || info.ContentType != "application/pdf" {
+		t.Errorf("unexpected info %+v", info)
+	}
+	if info.ETag != hex.EncodeToString(sum[:]) || string(info.MD5) != string(sum[:]) || info.CRC32C == 0 {
+		t.Errorf("unexpected checksums %+v", info)
+	}
+
+	if _, err := builder.Stat("bucket", "missing.pdf"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if _, err := builder.Stat("", "report.pdf"); err == nil {
+		t.Error("expected an empty bucket to be refused")
+	}
+}
+
+func TestExists(t *testing.T) {
+	builder := New(NewMemoryAdapter())
+	if _, err := builder.UploadReader("bucket", "report.csv", strings.NewReader("a,b"), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"report.csv", true},
+		{"missing.csv", false},
+	}
+	for _, tt := range tests {
+		isExist, err := builder.Exists("bucket", tt.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if isExist != tt.want {
+			t.Errorf("Exists(%q) = %v, want %v", tt.name, isExist, tt.want)
+		}
+	}
+
+	if _, err := builder.Exists("bucket", ""); err == nil {
+		t.Error("expected an empty name to be refused")
+	}
+}
+
+func TestLocalStat(t *testing.T) {
+	adapter, cleanup := newLocalTestAdapter(t)
+	defer cleanup()
+	builder := New(LocalClient{Root: adapter.Root})
+
+	if _, err := builder.UploadReader("bucket", "dir/report.csv", strings.NewReader("a,b\n"), ""); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := builder.Stat("bucket", "dir/report.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Size != 4 || info.ContentType != "text/csv" || info.Updated.IsZero() {
+		t.Errorf("unexpected info %+v", info)
+	}
+
+	for _, name := range []string{"missing.csv", "dir"} {
+		if _, err := builder.Stat("bucket", name); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Stat(%q): expected ErrNotFound, got %v", name, err)
+		}
+	}
+	if _, err := builder.Stat("bucket", "../escaped.csv"); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("expected a name escaping the bucket to be refused, got %v", err)
+	}
+}
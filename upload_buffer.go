@@ -2,24 +2,30 @@ package storage
 
 import (
 	"bytes"
-	"errors"
+	"context"
 	"fmt"
 	"io"
+	"os"
 	"strings"
 
 	s "cloud.google.com/go/storage"
-	oss "github.com/aliyun/aliyun-oss-go-sdk/oss"
 )
 
 // Buffer :
 type Buffer struct {
-	adapter       string      // gcs and aliyun
-	bucket        string      // gcs and aliyun
-	filename      string      // gcs and aliyun
-	storageWriter *s.Writer   // gcs
-	object        *oss.Bucket // aliyun
-	endpoint      string      // aliyun
-	position      int64       // aliyun
+	ctx           context.Context
+	adapter       string         // gcs, aliyun, local, memory, s3 and azure
+	bucket        string         // gcs, aliyun, local, memory, s3 and azure
+	filename      string         // gcs, aliyun, local, memory, s3 and azure
+	storageWriter *s.Writer      // gcs
+	gcs           *GCSAdapter    // gcs
+	aliyun        *AliyunAdapter // aliyun
+	position      int64          // aliyun and local
+	contentType   string         // aliyun
+	file          *os.File       // local
+	memory        *MemoryAdapter // memory
+	s3            *s3Upload      // s3
+	azure         *azureUpload   // azure
 }
 
 // Copy :
@@ -27,19 +33,58 @@ func (buf *Buffer) Copy(reader io.Reader) error {
 	switch buf.adapter {
 	case GCS:
 		if _, err := io.Copy(buf.storageWriter, reader); err != nil {
-			msg := fmt.Sprintf("Could not write file: %v", err)
-			return errors.New(msg)
+			msg := fmt.Errorf("Could not write file: %w", err)
+			return gcsError("upload", buf.bucket, buf.filename, msg)
 		}
 
 	case ALIYUN:
-		position, err := buf.object.AppendObject(buf.filename, reader, buf.position)
+		// the client is got again, in case the credentials were refreshed since the last copy
+		storageClient, err := buf.aliyun.getClient(buf.ctx)
 		if err != nil {
-			msg := fmt.Sprintf("Could not write file: %v", err)
-			return errors.New(msg)
+			return aliyunError("upload", buf.bucket, buf.filename, err)
+		}
+
+		object, err := storageClient.Bucket(buf.bucket)
+		if err != nil {
+			return aliyunError("upload", buf.bucket, buf.filename, err)
+		}
+
+		position, err := object.AppendObject(buf.filename, newContextReader(buf.ctx, reader), buf.position)
+		if err != nil {
+			msg := fmt.Errorf("Could not write file: %w", err)
+			return aliyunError("upload", buf.bucket, buf.filename, msg)
 		}
 		buf.position = position
+
+	case LOCAL:
+		n, err := io.Copy(buf.file, newContextReader(buf.ctx, reader))
+		if err != nil {
+			msg := fmt.Errorf("Could not write file: %w", err)
+			return localError("upload", buf.bucket, buf.filename, msg)
+		}
+		buf.position += n
+
+	case MEMORY:
+		if err := buf.memory.append(buf.bucket, buf.filename, newContextReader(buf.ctx, reader)); err != nil {
+			msg := fmt.Errorf("Could not write file: %w", err)
+			return memoryError("upload", buf.bucket, buf.filename, msg)
+		}
+
+	case S3:
+		if err := buf.s3.copy(newContextReader(buf.ctx, reader)); err != nil {
+			buf.s3.abort()
+			msg := fmt.Errorf("Could not write file: %w", err)
+			return s3Error("upload", buf.bucket, buf.filename, msg)
+		}
+
+	case AZURE:
+		if err := buf.azure.copy(newContextReader(buf.ctx, reader)); err != nil {
+			msg := fmt.Errorf("Could not write file: %w", err)
+			return azureError("upload", buf.bucket, buf.filename, msg)
+		}
+
 	default:
-		return errors.New("invalid adapter")
+		return fmt.Errorf("%w: invalid adapter %q", ErrInvalidClient, buf.adapter)
 	}
 
 	return nil
@@ -59,19 +104,58 @@ func (buf *Buffer) CopyString(data string) error {
 
 // Close :
 func (buf *Buffer) Close() (string, error) {
+	result, err := buf.Commit()
+	if err != nil {
+		return "", err
+	}
+
+	return result.URL, nil
+}
+
+// Commit : Same as Close, but return the canonical identifiers of the object
+func (buf *Buffer) Commit() (*UploadResult, error) {
 	switch buf.adapter {
 	case GCS:
 		if err := buf.storageWriter.Close(); err != nil {
-			msg := fmt.Sprintf("Could not put file: %v", err)
-			return "", errors.New(msg)
+			msg := fmt.Errorf("Could not put file: %w", err)
+			return nil, gcsError("upload", buf.bucket, buf.filename, msg)
 		}
 
-		return fmt.Sprintf("%s/%s/%s", googleGCSDomain, buf.bucket, buf.storageWriter.Name), nil
+		return buf.gcs.uploadResult(buf.storageWriter.Attrs()), nil
 
 	case ALIYUN:
-		return getAliyunFileURL(buf.endpoint, buf.bucket, buf.filename), nil
+		return buf.aliyun.uploadResult(buf.bucket, buf.filename, buf.position, buf.contentType), nil
+
+	case LOCAL:
+		if err := buf.file.Close(); err != nil {
+			msg := fmt.Errorf("Could not put file: %w", err)
+			return nil, localError("upload", buf.bucket, buf.filename, msg)
+		}
+
+		return localUploadResult(buf.bucket, buf.filename, buf.file.Name(), buf.position), nil
+
+	case MEMORY:
+		return buf.memory.uploadResult(buf.bucket, buf.filename)
+
+	case S3:
+		result, err := buf.s3.commit()
+		if err != nil {
+			msg := fmt.Errorf("Could not put file: %w", err)
+			return nil, s3Error("upload", buf.bucket, buf.filename, msg)
+		}
+
+		return result, nil
+
+	case AZURE:
+		result, err := buf.azure.commit()
+		if err != nil {
+			msg := fmt.Errorf("Could not put file: %w", err)
+			return nil, azureError("upload", buf.bucket, buf.filename, msg)
+		}
+
+		return result, nil
 
 	default:
-		return "", errors.New("invalid adapter")
+		return nil, fmt.Errorf("%w: invalid adapter %q", ErrInvalidClient, buf.adapter)
 	}
 }
@@ -0,0 +1,207 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Canned acl understood by every provider which has object acl, the others values
+// are passed to the provider as they are
+const (
+	ACLPrivate    = "private"
+	ACLPublicRead = "public-read"
+)
+
+// UploadOptions : The settings of an upload, built from the UploadOption of Upload.
+// ContentType is one of the ContentType constants, the empty fields are left to
+// the provider defaults
+type UploadOptions struct {
+	ContentType        string
+	ContentDisposition string
+	Attributes         ObjectAttributes
+	MD5                []byte
+	ACL                string
+	StorageClass       string
+	IfNotExists        bool
+}
+
+// UploadOption : Change a setting of the upload
+type UploadOption func(*UploadOptions)
+
+// Uploader : Implemented by the adapters which accept every setting of UploadOptions,
+// the settings a provider doesn't have are refused with ErrUnsupported
+type Uploader interface {
+	Upload(ctx context.Context, bucket, key string, reader io.Reader, options UploadOptions) (*UploadResult, error)
+}
+
+var _ Uploader = &GCSAdapter{}
+var _ Uploader = &AliyunAdapter{}
+var _ Uploader = &LocalAdapter{}
+var _ Uploader = &MemoryAdapter{}
+var _ Uploader = &S3Adapter{}
+var _ Uploader = &AzureBlobAdapter{}
+
+// WithContentType : One of the ContentType constants, same as the contentType of Put
+func WithContentType(contentType string) UploadOption {
+	return func(options *UploadOptions) {
+		options.ContentType = contentType
+	}
+}
+
+// WithContentDisposition : Replace the disposition given by the content type
+func WithContentDisposition(disposition string) UploadOption {
+	return func(options *UploadOptions) {
+		options.ContentDisposition = disposition
+	}
+}
+
+// WithCacheControl :
+func WithCacheControl(cacheControl string) UploadOption {
+	return func(options *UploadOptions) {
+		options.Attributes.CacheControl = cacheControl
+	}
+}
+
+// WithContentEncoding :
+func WithContentEncoding(encoding string) UploadOption {
+	return func(options *UploadOptions) {
+		options.Attributes.ContentEncoding = encoding
+	}
+}
+
+// WithContentLanguage :
+func WithContentLanguage(language string) UploadOption {
+	return func(options *UploadOptions) {
+		options.Attributes.ContentLanguage = language
+	}
+}
+
+// WithMetadata : The custom metadata of the object, see ObjectAttributes
+func WithMetadata(metadata map[string]string) UploadOption {
+	return func(options *UploadOptions) {
+		options.Attributes.Metadata = metadata
+	}
+}
+
+// WithTags : The tags of the object, only supported by s3, azure and memory
+func WithTags(tags map[string]string) UploadOption {
+	return func(options *UploadOptions) {
+		options.Attributes.Tags = tags
+	}
+}
+
+// WithAttributes : Replace every attribute at once, same as PutWithAttributes
+func WithAttributes(attrs ObjectAttributes) UploadOption {
+	return func(options *UploadOptions) {
+		options.Attributes = attrs
+	}
+}
+
+// WithChecksum : The md5 of the data, the upload fails with ErrChecksumMismatch
+// and the object is not written when the data doesn't match
+func WithChecksum(md5 []byte) UploadOption {
+	return func(options *UploadOptions) {
+		options.MD5 = md5
+	}
+}
+
+// WithACL : ACLPrivate, ACLPublicRead or an acl of the provider, azure has no object acl
+func WithACL(acl string) UploadOption {
+	return func(options *UploadOptions) {
+		options.ACL = acl
+	}
+}
+
+// WithStorageClass : The storage class of the provider, e.g. "NEARLINE" on gcs,
+// "IA" on aliyun, "STANDARD_IA" on s3 or the "Cool" access tier on azure
+func WithStorageClass(storageClass string) UploadOption {
+	return func(options *UploadOptions) {
+		options.StorageClass = storageClass
+	}
+}
+
+// WithIfNotExists : Fail with ErrPreconditionFailed instead of replacing an existing object
+func WithIfNotExists() UploadOption {
+	return func(options *UploadOptions) {
+		options.IfNotExists = true
+	}
+}
+
+// Upload : Upload the reader with the options and return the canonical identifiers
+// of the object
+func (b *Builder) Upload(ctx context.Context, bucket, key string, reader io.Reader, options ...UploadOption) (*UploadResult, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	var (
+		errNameIsRequired   = errors.New("storage: filename is required")
+		errBucketIsRequired = errors.New("storage: bucket is required")
+		errReaderIsNil      = errors.New("storage: io reader is nil")
+	)
+
+	if len(key) == 0 {
+		return nil, errNameIsRequired
+	}
+
+	if len(bucket) == 0 {
+		return nil, errBucketIsRequired
+	}
+
+	if reader == nil {
+		return nil, errReaderIsNil
+	}
+
+	var uploadOptions UploadOptions
+	for _, option := range options {
+		option(&uploadOptions)
+	}
+
+	if uploader, ok := b.adapter.(Uploader); ok {
+		return uploader.Upload(ctx, bucket, key, reader, uploadOptions)
+	}
+
+	// the adapters registered outside of the package may only have Put
+	rest := uploadOptions
+	rest.ContentType = ""
+	rest.Attributes = ObjectAttributes{}
+	if !rest.isZero() {
+		return nil, fmt.Errorf("%w: %T only accepts the content type and attributes", ErrUnsupported, b.adapter)
+	}
+
+	if uploadOptions.Attributes.isZero() {
+		return b.adapter.Put(ctx, bucket, key, reader, uploadOptions.ContentType)
+	}
+
+	putter, ok := b.adapter.(AttributesPutter)
+	if !ok {
+		return nil, fmt.Errorf("%w: %T can't set object attributes", ErrUnsupported, b.adapter)
+	}
+
+	return putter.PutWithAttributes(ctx, bucket, key, reader, uploadOptions.ContentType, uploadOptions.Attributes)
+}
+
+func (options UploadOptions) isZero() bool {
+	return options.ContentType == "" && options.ContentDisposition == "" && options.Attributes.isZero() &&
+		options.MD5 == nil && options.ACL == "" && options.StorageClass == "" && !options.IfNotExists
+}
+
+// verify : Compare the md5 of the data read so far with the expected one, for the
+// providers which can't check it themselves
+func (r *hashReader) verify(expected []byte) error {
+	if expected == nil {
+		return nil
+	}
+
+	if sum := r.md5.Sum(nil); !bytes.Equal(sum, expected) {
+		return fmt.Errorf("%w: expected md5 %x, got %x", ErrChecksumMismatch, expected, sum)
+	}
+
+	return nil
+}
+
+func errUploadOptionNotSupported(provider, option string) error {
+	return fmt.Errorf("%w: %s has no %s", ErrUnsupported, provider, option)
+}
@@ -0,0 +1,196 @@
+package storage
+
+import (
+	"context"
+	"crypto/md5"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+// putOnlyAdapter : An adapter registered outside of the package, which only has Put
+type putOnlyAdapter struct {
+	Adapter
+}
+
+func TestMemoryUploadOptions(t *testing.T) {
+	ctx := context.Background()
+	adapter := NewMemoryAdapter()
+	builder := New(adapter)
+	sum := md5.Sum([]byte("a,b"))
+
+	_, err := builder.Upload(ctx, "bucket", "report.csv", strings.NewReader("a,b"),
+		WithContentType(ContentTypeCSV),
+		WithContentDisposition("inline"),
+		WithCacheControl("no-cache"),
+		WithMetadata(map[string]string{"tenant": "a"}),
+		WithACL(ACLPublicRead),
+		WithStorageClass("NEARLINE"),
+		WithChecksum(sum[:]),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	object, _ := adapter.Object("bucket", "report.csv")
+	if object.ContentDisposition != "inline" || object.CacheControl != "no-cache" || object.Metadata["tenant"] != "a" ||
+		object.ACL != ACLPublicRead || object.StorageClass != "NEARLINE" {
+		t.Errorf("unexpected object %+v", object)
+	}
+
+	if _, err := builder.Upload(ctx, "bucket", "report.csv", strings.NewReader("c,d"), WithIfNotExists()); !errors.Is(err, ErrPreconditionFailed) {
+		t.Errorf("expected ErrPreconditionFailed, got %v", err)
+	}
+	if _, err := builder.Upload(ctx, "bucket", "other.csv", strings.NewReader("c,d"), WithChecksum(sum[:])); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("expected ErrChecksumMismatch, got %v", err)
+	}
+	if _, isExist := adapter.Object("bucket", "other.csv"); isExist {
+		t.Error("expected the object failing the checksum not to be written")
+	}
+	if object, _ := adapter.Object("bucket", "report.csv"); string(object.Data) != "a,b" {
+		t.Errorf("expected the existing object to be kept, got %q", object.Data)
+	}
+}
+
+func TestLocalUploadOptions(t *testing.T) {
+	ctx := context.Background()
+	adapter, cleanup := newLocalTestAdapter(t)
+	defer cleanup()
+	builder := New(LocalClient{Root: adapter.Root})
+
+	if _, err := builder.Upload(ctx, "bucket", "report.csv", strings.NewReader("a,b"), WithIfNotExists()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := builder.Upload(ctx, "bucket", "report.csv", strings.NewReader("c,d"), WithIfNotExists()); !errors.Is(err, ErrPreconditionFailed) {
+		t.Errorf("expected ErrPreconditionFailed, got %v", err)
+	}
+
+	sum := md5.Sum([]byte("a,b"))
+	if _, err := builder.Upload(ctx, "bucket", "other.csv", strings.NewReader("c,d"), WithChecksum(sum[:])); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("expected ErrChecksumMismatch, got %v", err)
+	}
+	path, _ := adapter.getFilePath("bucket", "other.csv")
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected the file failing the checksum to be removed, got %v", err)
+	}
+
+	for _, option := range []UploadOption{WithACL(ACLPrivate), WithStorageClass("COLD"), WithCacheControl("no-cache")} {
+		if _, err := builder.Upload(ctx, "bucket", "report.csv", strings.NewReader("a,b"), option); !errors.Is(err, ErrUnsupported) {
+			t.Errorf("expected ErrUnsupported, got %v", err)
+		}
+	}
+}
+
+func TestUploadWithPutOnlyAdapter(t *testing.T) {
+	ctx := context.Background()
+	memory := NewMemoryAdapter()
+	builder := &Builder{adapter: putOnlyAdapter{memory}}
+
+	if _, err := builder.Upload(ctx, "bucket", "report.csv", strings.NewReader("a,b"), WithContentType(ContentTypeCSV)); err != nil {
+		t.Fatal(err)
+	}
+	if object, _ := memory.Object("bucket", "report.csv"); string(object.Data) != "a,b" {
+		t.Errorf("unexpected object %+v", object)
+	}
+
+	if _, err := builder.Upload(ctx, "bucket", "report.csv", strings.NewReader("a,b"), WithIfNotExists()); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("expected ErrUnsupported, got %v", err)
+	}
+	if _, err := builder.Upload(ctx, "bucket", "report.csv", strings.NewReader("a,b"), WithCacheControl("no-cache")); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("expected the attributes to be refused, got %v", err)
+	}
+}
+
+func TestUploadIsValidated(t *testing.T) {
+	builder := New(NewMemoryAdapter())
+	ctx := context.Background()
+
+	if _, err := builder.Upload(ctx, "bucket", "", strings.NewReader("a,b")); err == nil {
+		t.Error("expected the key to be required")
+	}
+	if _, err := builder.Upload(ctx, "", "report.csv", strings.NewReader("a,b")); err == nil {
+		t.Error("expected the bucket to be required")
+	}
+	if _, err := builder.Upload(ctx, "bucket", "report.csv", nil); err == nil {
+		t.Error("expected the reader to be required")
+	}
+}
+
+func TestS3UploadOptions(t *testing.T) {
+	fake, adapter, stop := newFakeS3(t)
+	defer stop()
+
+	var header http.Header
+	fake.handler = func(w http.ResponseWriter, r *http.Request, body []byte) bool {
+		header = r.Header
+		if _, isExist := fake.objects["report.csv"]; isExist && r.Header.Get("If-None-Match") == "*" {
+			writeS3Error(w, http.StatusPreconditionFailed, "PreconditionFailed")
+			return true
+		}
+		return false
+	}
+
+	_, err := adapter.Upload(context.Background(), "bucket", "report.csv", strings.NewReader("a,b"), UploadOptions{
+		ContentDisposition: "inline",
+		ACL:                ACLPublicRead,
+		StorageClass:       "STANDARD_IA",
+		IfNotExists:        true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]string{"Content-Disposition": "inline", "X-Amz-Acl": ACLPublicRead, "X-Amz-Storage-Class": "STANDARD_IA", "If-None-Match": "*"} {
+		if got := header.Get(key); got != want {
+			t.Errorf("%s = %q, want %q", key, got, want)
+		}
+	}
+
+	if _, err := adapter.Upload(context.Background(), "bucket", "report.csv", strings.NewReader("a,b"), UploadOptions{IfNotExists: true}); !errors.Is(err, ErrPreconditionFailed) {
+		t.Errorf("expected ErrPreconditionFailed, got %v", err)
+	}
+
+	sum := md5.Sum([]byte("a,b"))
+	fake.requests = nil
+	if _, err := adapter.Upload(context.Background(), "bucket", "other.csv", strings.NewReader("c,d"), UploadOptions{MD5: sum[:]}); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("expected ErrChecksumMismatch, got %v", err)
+	}
+	if len(fake.requests) != 0 {
+		t.Errorf("expected the object failing the checksum not to be sent, got %v", fake.requests)
+	}
+}
+
+func TestAliyunUploadTagsAndIfNotExists(t *testing.T) {
+	var header http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header
+		if r.Header.Get("X-Oss-Forbid-Overwrite") == "true" {
+			w.WriteHeader(http.StatusConflict)
+			w.Write([]byte("<Error><Code>FileAlreadyExists</Code><Message>The object you specified already exists and can not be overwritten.</Message></Error>"))
+			return
+		}
+		w.Header().Set("ETag", `"etag"`)
+	}))
+	defer server.Close()
+	adapter := &AliyunAdapter{Endpoint: server.URL, AccessKeyID: "id", AccessKeySecret: "secret"}
+
+	_, err := adapter.Upload(context.Background(), "bucket", "report.csv", strings.NewReader("a,b"), UploadOptions{
+		ContentType: ContentTypeCSV,
+		Attributes:  ObjectAttributes{Tags: map[string]string{"tenant": "a", "team": "b c"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := header.Get("X-Oss-Tagging"); got != "team=b+c&tenant=a" {
+		t.Errorf("unexpected tags %q", got)
+	}
+	if header.Get("X-Oss-Forbid-Overwrite") != "" {
+		t.Error("expected the object to be overwritten by default")
+	}
+
+	_, err = adapter.Upload(context.Background(), "bucket", "report.csv", strings.NewReader("a,b"), UploadOptions{ContentType: ContentTypeCSV, IfNotExists: true})
+	if !errors.Is(err, ErrPreconditionFailed) {
+		t.Errorf("expected ErrPreconditionFailed, got %v", err)
+	}
+}
@@ -0,0 +1,121 @@
+package storage
+
+import (
+	"context"
+	"crypto/md5"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"mime"
+	"mime/multipart"
+	"strings"
+)
+
+// UploadResult : The canonical identifiers of an uploaded object, the fields
+// a provider doesn't report are left empty
+type UploadResult struct {
+	Bucket      string
+	Key         string
+	URL         string
+	Size        int64
+	ContentType string
+	ETag        string
+	MD5         []byte
+	CRC32C      uint32
+	Generation  int64  // gcs
+	VersionID   string // aliyun, s3 and azure, only when the bucket is versioned
+}
+
+// hashReader : Compute the size and checksums while the data is being uploaded,
+// for the providers which don't return them
+type hashReader struct {
+	reader io.Reader
+	md5    hash.Hash
+	crc32c hash.Hash32
+	size   int64
+}
+
+func newHashReader(reader io.Reader) *hashReader {
+	return &hashReader{
+		reader: reader,
+		md5:    md5.New(),
+		crc32c: crc32.New(crc32.MakeTable(crc32.Castagnoli)),
+	}
+}
+
+func (r *hashReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		r.md5.Write(p[:n])
+		r.crc32c.Write(p[:n])
+		r.size += int64(n)
+	}
+	return n, err
+}
+
+// uploadMultipartFile : Name the object after the content type detected from the
+// data of the form file, e.g. "avatar" holding a png image becomes "avatar.png".
+// The Content-Type sent by the client is only used for the text formats, which
+// have no signature, and for the extension when the type stays unknown. The upload
+// goes through Builder.Upload, so it is checked the same way
+func uploadMultipartFile(ctx context.Context, adapter Adapter, file *multipart.FileHeader, bucket, filename string) (*UploadResult, error) {
+	src, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+
+	defer src.Close()
+
+	head, reader, err := peekContent(src)
+	if err != nil {
+		return nil, err
+	}
+
+	contentType := ""
+	detected := detectContentType(head)
+	if key, isExist := contentTypeKeys[contentTypeMediaType(detected)]; isExist {
+		contentType = key
+	} else if strings.HasPrefix(detected, "text/plain") {
+		if key, isExist := contentTypeKeys[contentTypeMediaType(file.Header.Get("Content-Type"))]; isExist && textContentTypes[key] {
+			contentType = key
+		}
+	}
+
+	ext := contentType
+	if ext == "" {
+		ext = headerExtension(file.Header.Get("Content-Type"))
+	}
+
+	name := filename
+	if mapped, isExist := extensionMapper[ext]; isExist {
+		name = fmt.Sprintf("%s.%s", filename, mapped)
+	} else if ext != "" {
+		name = fmt.Sprintf("%s.%s", filename, ext)
+	}
+
+	builder := &Builder{adapter: adapter}
+	return builder.Upload(ctx, bucket, name, reader, WithContentType(contentType))
+}
+
+// headerExtension : The subtype of the Content-Type sent by the client, e.g. "webp"
+// for "image/webp", empty when the header is missing or malformed
+func headerExtension(header string) string {
+	mediaType, _, err := mime.ParseMediaType(header)
+	if err != nil {
+		return ""
+	}
+
+	i := strings.Index(mediaType, "/")
+	if i < 0 {
+		return ""
+	}
+	return mediaType[i+1:]
+}
+
+// textContentTypes : The content types http.DetectContentType reports as text/plain
+var textContentTypes = map[string]bool{
+	ContentTypeCSV: true,
+	ContentTypeCSS: true,
+	ContentTypeJS:  true,
+}
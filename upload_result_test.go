@@ -0,0 +1,168 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"hash/crc32"
+	"io/ioutil"
+	"mime/multipart"
+	"net/textproto"
+	"strings"
+	"testing"
+)
+
+func newFileHeader(t *testing.T, contentType string, data []byte) *multipart.FileHeader {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", `form-data; name="file"; filename="upload"`)
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(data)
+	writer.Close()
+
+	form, err := multipart.NewReader(&body, writer.Boundary()).ReadForm(1 << 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return form.File["file"][0]
+}
+
+func TestHashReader(t *testing.T) {
+	data := []byte("a,b\n1,2\n")
+	hr := newHashReader(bytes.NewReader(data))
+	if _, err := ioutil.ReadAll(hr); err != nil {
+		t.Fatal(err)
+	}
+
+	sum := md5.Sum(data)
+	if !bytes.Equal(hr.md5.Sum(nil), sum[:]) {
+		t.Errorf("md5 = %x, want %x", hr.md5.Sum(nil), sum)
+	}
+	if want := crc32.Checksum(data, crc32.MakeTable(crc32.Castagnoli)); hr.crc32c.Sum32() != want {
+		t.Errorf("crc32c = %d, want %d", hr.crc32c.Sum32(), want)
+	}
+	if hr.size != int64(len(data)) {
+		t.Errorf("size = %d, want %d", hr.size, len(data))
+	}
+}
+
+func TestPutResult(t *testing.T) {
+	ctx := context.Background()
+	data := "a,b\n1,2\n"
+	sum := md5.Sum([]byte(data))
+
+	adapter, cleanup := newLocalTestAdapter(t)
+	defer cleanup()
+
+	for _, builder := range []*Builder{New(NewMemoryAdapter()), New(LocalClient{Root: adapter.Root})} {
+		result, err := builder.Put(ctx, "bucket", "dir/report.csv", strings.NewReader(data), "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Bucket != "bucket" || result.Key != "dir/report.csv" || result.Size != int64(len(data)) || result.ContentType != "text/csv" {
+			t.Errorf("unexpected result %+v", result)
+		}
+		if !bytes.Equal(result.MD5, sum[:]) || result.ETag != hex.EncodeToString(sum[:]) || result.CRC32C == 0 {
+			t.Errorf("unexpected checksums %+v", result)
+		}
+		if !strings.HasSuffix(result.URL, "bucket/dir/report.csv") {
+			t.Errorf("unexpected url %q", result.URL)
+		}
+
+		fileURL, err := builder.UploadReader("bucket", "dir/report.csv", strings.NewReader(data), "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fileURL != result.URL {
+			t.Errorf("UploadReader returned %q, Put returned %q", fileURL, result.URL)
+		}
+	}
+}
+
+func TestBufferCommit(t *testing.T) {
+	adapter, cleanup := newLocalTestAdapter(t)
+	defer cleanup()
+
+	for _, builder := range []*Builder{New(NewMemoryAdapter()), New(LocalClient{Root: adapter.Root})} {
+		buf, err := builder.UploadBuffer("bucket", "log.csv", ContentTypeCSV)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := buf.CopyString("a,b\n"); err != nil {
+			t.Fatal(err)
+		}
+		if err := buf.CopyString("1,2\n"); err != nil {
+			t.Fatal(err)
+		}
+
+		result, err := buf.Commit()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if result.Key != "log.csv" || result.Size != 8 || result.ContentType != "text/csv" {
+			t.Errorf("unexpected result %+v", result)
+		}
+	}
+}
+
+func TestPutFile(t *testing.T) {
+	adapter := NewMemoryAdapter()
+	file := newFileHeader(t, "text/csv", []byte("a,b\n"))
+
+	result, err := New(adapter).PutFile(context.Background(), file, "bucket", "report")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Key != "report.csv" || result.Size != 4 {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if _, isExist := adapter.Object("bucket", "report.csv"); !isExist {
+		t.Error("the form file is not stored")
+	}
+}
+
+func TestUploadMultipartFileName(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	unknown := []byte{0x00, 0x01, 0x02, 0x03}
+
+	tests := []struct {
+		contentType string
+		data        []byte
+		want        string
+	}{
+		{"application/octet-stream", png, "avatar.png"},
+		{"image/webp", unknown, "avatar.webp"},
+		{"text/csv", []byte("a,b\n1,2\n"), "avatar.csv"},
+		{"", unknown, "avatar"},
+		{"image", unknown, "avatar"},
+		{"image/webp/x", unknown, "avatar"},
+	}
+
+	for _, tt := range tests {
+		adapter := NewMemoryAdapter()
+		result, err := uploadMultipartFile(context.Background(), adapter, newFileHeader(t, tt.contentType, tt.data), "bucket", "avatar")
+		if err != nil {
+			t.Errorf("%q: %v", tt.contentType, err)
+			continue
+		}
+		if result.Key != tt.want {
+			t.Errorf("%q: key = %q, want %q", tt.contentType, result.Key, tt.want)
+		}
+	}
+}
+
+func TestUploadMultipartFileIsValidated(t *testing.T) {
+	file := newFileHeader(t, "image/png", []byte("data"))
+	if _, err := uploadMultipartFile(context.Background(), NewMemoryAdapter(), file, "", "avatar"); err == nil {
+		t.Error("expected an error for an empty bucket")
+	}
+}
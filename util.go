@@ -1,8 +1,12 @@
 package storage
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
 )
 
 // ParseBase64GoogleCredential :
@@ -19,3 +23,42 @@ func ParseBase64GoogleCredential(data string) (*GoogleClient, error) {
 
 	return googleClient, nil
 }
+
+// readGoogleCredentialFile : Read the service account json, as downloaded from the console
+func readGoogleCredentialFile(path string) (*GoogleClient, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidClient, err)
+	}
+
+	googleClient := new(GoogleClient)
+	if err := json.Unmarshal(data, googleClient); err != nil {
+		return nil, fmt.Errorf("%w: invalid google credential %s: %v", ErrInvalidClient, path, err)
+	}
+
+	return googleClient, nil
+}
+
+// readCloser : Close the underlying object of a wrapped reader
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// contextReader : Fail the read once the context is done, for the sdk which
+// has no context support of its own
+type contextReader struct {
+	ctx    context.Context
+	reader io.Reader
+}
+
+func newContextReader(ctx context.Context, reader io.Reader) io.Reader {
+	return &contextReader{ctx: ctx, reader: reader}
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.reader.Read(p)
+}